@@ -62,14 +62,50 @@ type Expr interface {
 // All SQL queries implement the Query interface.
 type Query interface {
 	Node
+	QueryComments() *Comments
 	queryNode()
 }
 
+// Comment is a comment as it is written in the code: -- line,
+// # line or /* block */, including the delimiters.
+type Comment struct {
+	Pos  Position
+	Text string
+}
+
+// Comments are the comments written before a query and after it until
+// the end of the line of the semicolon. Comments inside the query are
+// ignored.
+type Comments struct {
+	Leading  []*Comment
+	Trailing []*Comment
+}
+
+// QueryComments returns the comments of the query.
+func (c *Comments) QueryComments() *Comments {
+	return c
+}
+
 type SqlFrom interface {
 	Node
 	fromNode()
 }
 
+// DerivedTable is a subquery used as a table in FROM or JOIN.
+// The alias is a local name that is never prefixed.
+type DerivedTable struct {
+	Pos   Position
+	Query *SelectQuery
+	Alias string
+	Joins []*Join
+}
+
+func (a *DerivedTable) Position() Position {
+	return a.Pos
+}
+func (a *DerivedTable) fromNode() {}
+
+// **DEPRECATE** the parser returns a DerivedTable for subqueries.
 type FromAsExpr struct {
 	From  *ParenExpr
 	Alias string
@@ -109,7 +145,7 @@ type OrderColumn struct {
 
 type ParameterExpr struct {
 	Pos  Position
-	Name string
+	Name string // the name of :name, @name or $name without the prefix
 }
 
 func (q *ParameterExpr) Position() Position {
@@ -172,7 +208,10 @@ type Join struct {
 	Table    string
 	Alias    string
 	Database string
+	Subquery *DerivedTable // if it joins a subquery instead of a table
+	Natural  bool
 	On       Expr
+	Using    []string
 }
 
 type WherePart struct {
@@ -198,16 +237,75 @@ const (
 	Bool
 	Blob
 	DatTime
+	BigInt
+	SmallInt
+	Float
+	Double
+	Date
+	Time
+	Timestamp
+	JSON
+	UUID
+	Enum
+	LongText
+	LongBlob
 )
 
+// isInteger returns true for the integer types.
+func (t ColumnType) isInteger() bool {
+	switch t {
+	case Int, BigInt, SmallInt:
+		return true
+	}
+	return false
+}
+
+// isNumeric returns true for the types that can be UNSIGNED.
+func (t ColumnType) isNumeric() bool {
+	switch t {
+	case Decimal, Float, Double:
+		return true
+	}
+	return t.isInteger()
+}
+
 type CreateColumn struct {
 	Name     string
 	Type     ColumnType
 	Nullable bool
 	Size     string
 	Decimals string
-	Key      bool
-	Default  string
+	Unsigned bool
+
+	// Values are the values of an ENUM.
+	Values []string
+
+	// Key is the shortcut "id key": an int primary key with auto increment.
+	Key bool
+
+	// PrimaryKey is a column level PRIMARY KEY.
+	PrimaryKey bool
+
+	// AutoIncrement requires the column to be an int of the primary key.
+	AutoIncrement bool
+
+	// Default is a constant, a keyword as CURRENT_TIMESTAMP or an
+	// expression in parenthesis.
+	Default Expr
+
+	// OnUpdate is the ON UPDATE CURRENT_TIMESTAMP of MySQL.
+	OnUpdate Expr
+
+	// Check is the expression of a column CHECK constraint.
+	Check Expr
+
+	// Comment is only written by the databases that support it.
+	Comment string
+
+	// Generated is the expression of a GENERATED ALWAYS AS column.
+	// It is VIRTUAL unless Stored is true.
+	Generated Expr
+	Stored    bool
 }
 
 type Constraint struct {
@@ -218,6 +316,14 @@ type Constraint struct {
 
 func (Constraint) constraintNode() {}
 
+// Check is a table CHECK constraint. The name is optional.
+type Check struct {
+	Name string
+	Expr Expr
+}
+
+func (Check) constraintNode() {}
+
 type ForeginKey struct {
 	Name          string
 	Column        string
@@ -241,6 +347,7 @@ type CreateDatabaseQuery struct {
 	Pos         Position
 	Name        string
 	IfNotExists bool
+	Comments
 }
 
 func (q *CreateDatabaseQuery) Position() Position {
@@ -253,8 +360,10 @@ type CreateTableQuery struct {
 	Pos         Position
 	Name        string
 	Columns     []*CreateColumn
+	PrimaryKey  []string // a table level PRIMARY KEY (a, b)
 	Constraints []CreateTableConstraint
 	IfNotExists bool
+	Comments
 }
 
 func (q *CreateTableQuery) Position() Position {
@@ -268,6 +377,7 @@ type ShowQuery struct {
 	Type     string
 	Database string
 	Table    string
+	Comments
 }
 
 func (q *ShowQuery) Position() Position {
@@ -280,6 +390,7 @@ type DropDatabaseQuery struct {
 	Pos      Position
 	Database string
 	IfExists bool
+	Comments
 }
 
 func (q *DropDatabaseQuery) Position() Position {
@@ -293,6 +404,7 @@ type DropTableQuery struct {
 	Database string
 	Table    string
 	IfExists bool
+	Comments
 }
 
 func (q *DropTableQuery) Position() Position {
@@ -301,12 +413,54 @@ func (q *DropTableQuery) Position() Position {
 
 func (q *DropTableQuery) queryNode() {}
 
+// IndexColumn is a column of an index. Length is the size of a
+// prefix index or "" to index the whole value.
+type IndexColumn struct {
+	Name   string
+	Length string
+}
+
+type CreateIndexQuery struct {
+	Pos         Position
+	Name        string
+	Database    string
+	Table       string
+	Unique      bool
+	IfNotExists bool
+	Columns     []*IndexColumn
+	Comments
+}
+
+func (q *CreateIndexQuery) Position() Position {
+	return q.Pos
+}
+
+func (q *CreateIndexQuery) queryNode() {}
+
+// DropIndexQuery is DROP INDEX name [ON table]. The table is optional
+// but MySQL requires it because its indexes belong to a table.
+type DropIndexQuery struct {
+	Pos      Position
+	Name     string
+	Database string
+	Table    string
+	IfExists bool
+	Comments
+}
+
+func (q *DropIndexQuery) Position() Position {
+	return q.Pos
+}
+
+func (q *DropIndexQuery) queryNode() {}
+
 type AlterDropQuery struct {
 	Pos      Position
 	Database string
 	Table    string
 	Type     string
 	Item     string
+	Comments
 }
 
 func (q *AlterDropQuery) Position() Position {
@@ -320,6 +474,7 @@ type AddColumnQuery struct {
 	Database string
 	Table    string
 	Column   *CreateColumn
+	Comments
 }
 
 func (q *AddColumnQuery) Position() Position {
@@ -334,6 +489,7 @@ type RenameColumnQuery struct {
 	Table    string
 	Name     string
 	Column   *CreateColumn
+	Comments
 }
 
 func (q *RenameColumnQuery) Position() Position {
@@ -347,6 +503,7 @@ type ModifyColumnQuery struct {
 	Database string
 	Table    string
 	Column   *CreateColumn
+	Comments
 }
 
 func (q *ModifyColumnQuery) Position() Position {
@@ -362,6 +519,7 @@ type AddConstraintQuery struct {
 	Table    string
 	Name     string
 	Columns  []*ColumnNameExpr
+	Comments
 }
 
 func (q *AddConstraintQuery) Position() Position {
@@ -381,6 +539,7 @@ type AddFKQuery struct {
 	RefTable      string
 	RefColumn     string
 	DeleteCascade bool
+	Comments
 }
 
 func (q *AddFKQuery) Position() Position {
@@ -389,8 +548,23 @@ func (q *AddFKQuery) Position() Position {
 
 func (q *AddFKQuery) queryNode() {}
 
+// WithClause are the common table expressions of a query.
+type WithClause struct {
+	Pos       Position
+	Recursive bool
+	Tables    []*CommonTableExpr
+}
+
+type CommonTableExpr struct {
+	Pos     Position
+	Name    string
+	Columns []string
+	Query   *SelectQuery
+}
+
 type SelectQuery struct {
 	Pos         Position
+	With        *WithClause
 	Distinct    bool
 	ForUpdate   bool
 	Columns     []Expr
@@ -398,10 +572,21 @@ type SelectQuery struct {
 	WherePart   *WherePart
 	GroupByPart []Expr
 	HavingPart  *WherePart
+	Windows     []*WindowDef
 	OrderByPart []*OrderColumn
 	LimitPart   *Limit
-	UnionPart   []*SelectQuery
+	UnionPart   []*SetOperation
 	Params      []interface{}
+	Comments
+}
+
+// SetOperation combines the rows of a select with the previous ones.
+// The ORDER BY and LIMIT of the first select apply to the whole compound.
+type SetOperation struct {
+	Pos   Position
+	Type  Type // UNION, INTERSECT or EXCEPT
+	All   bool
+	Query *SelectQuery
 }
 
 func (q *SelectQuery) GetParams() []interface{} {
@@ -434,14 +619,38 @@ func (q *SelectQuery) RemoveLeftJoins() {
 func (q *SelectQuery) queryNode() {}
 
 type InsertQuery struct {
-	Pos     Position
-	Table   *TableName
-	Columns []*ColumnNameExpr
-	Values  []Expr
-	Params  []interface{}
-	Select  *SelectQuery // in case is a insert from a select
+	Pos        Position
+	Table      *TableName
+	Columns    []*ColumnNameExpr
+	Rows       [][]Expr // the tuples of VALUES (...), (...)
+	OnConflict *OnConflict
+	Params     []interface{}
+	Select     *SelectQuery // in case is a insert from a select
+	Comments
+}
+
+// OnConflict is the upsert clause of an INSERT: ON DUPLICATE KEY UPDATE
+// in MySQL and ON CONFLICT in SQLite and PostgreSQL.
+type OnConflict struct {
+	Pos       Position
+	Columns   []string // the conflict target. MySQL ignores it.
+	DoNothing bool
+	Update    []ColumnValue
 }
 
+// ExcludedExpr is the value that an upsert tried to insert in a column:
+// VALUES(col) in MySQL and excluded.col in SQLite and PostgreSQL.
+type ExcludedExpr struct {
+	Pos  Position
+	Name string
+}
+
+func (e *ExcludedExpr) Position() Position {
+	return e.Pos
+}
+
+func (e *ExcludedExpr) exprNode() {}
+
 func (q *InsertQuery) Position() Position {
 	return q.Pos
 }
@@ -450,11 +659,13 @@ func (q *InsertQuery) queryNode() {}
 
 type UpdateQuery struct {
 	Pos       Position
+	With      *WithClause
 	Table     *Table
 	Columns   []ColumnValue
 	WherePart *WherePart
 	LimitPart *Limit
 	Params    []interface{}
+	Comments
 }
 
 func (q *UpdateQuery) Position() Position {
@@ -465,11 +676,13 @@ func (q *UpdateQuery) queryNode() {}
 
 type DeleteQuery struct {
 	Pos       Position
+	With      *WithClause
 	Alias     []string
 	Table     *Table
 	WherePart *WherePart
 	LimitPart *Limit
 	Params    []interface{}
+	Comments
 }
 
 func (q *DeleteQuery) Position() Position {
@@ -502,6 +715,19 @@ func (i *BetweenExpr) Position() Position {
 }
 func (i *BetweenExpr) exprNode() {}
 
+// EscapeExpr is the pattern of a LIKE or ILIKE with an ESCAPE
+// character. It is the right side of the BinaryExpr.
+type EscapeExpr struct {
+	Pos     Position
+	Pattern Expr
+	Escape  Expr
+}
+
+func (i *EscapeExpr) Position() Position {
+	return i.Pos
+}
+func (i *EscapeExpr) exprNode() {}
+
 type InExpr struct {
 	LParen Position
 	Values []Expr
@@ -572,6 +798,7 @@ type CallExpr struct {
 	Pos  Position
 	Name string
 	Args []Expr
+	Over *OverClause // only in window functions
 }
 
 func (i *CallExpr) Position() Position {
@@ -579,6 +806,90 @@ func (i *CallExpr) Position() Position {
 }
 func (i *CallExpr) exprNode() {}
 
+// CastExpr converts an expression to a type: CAST(x AS type) or CONVERT(x, type).
+type CastExpr struct {
+	Pos      Position
+	Expr     Expr
+	Type     ColumnType
+	Size     string
+	Decimals string
+	Unsigned bool
+}
+
+func (c *CastExpr) Position() Position {
+	return c.Pos
+}
+
+func (c *CastExpr) exprNode() {}
+
+// ExistsExpr is an [NOT] EXISTS (SELECT ...) predicate.
+type ExistsExpr struct {
+	Pos   Position
+	Not   bool
+	Query *SelectQuery
+}
+
+func (i *ExistsExpr) Position() Position {
+	return i.Pos
+}
+func (i *ExistsExpr) exprNode() {}
+
+// CaseExpr is a CASE expression. If Value is nil it is a searched
+// CASE WHEN cond THEN ... and if not a simple CASE value WHEN x THEN ...
+type CaseExpr struct {
+	Pos   Position
+	Value Expr
+	Whens []*WhenClause
+	Else  Expr
+}
+
+func (i *CaseExpr) Position() Position {
+	return i.Pos
+}
+func (i *CaseExpr) exprNode() {}
+
+type WhenClause struct {
+	Pos    Position
+	Cond   Expr
+	Result Expr
+}
+
+// OverClause is the window of a window function. Name is the window
+// defined in the WINDOW clause of the query that it references or extends.
+type OverClause struct {
+	Pos         Position
+	Name        string
+	PartitionBy []Expr
+	OrderByPart []*OrderColumn
+	Frame       *WindowFrame
+}
+
+func (o *OverClause) isEmpty() bool {
+	return len(o.PartitionBy) == 0 && len(o.OrderByPart) == 0 && o.Frame == nil
+}
+
+// WindowFrame is a ROWS or RANGE frame. If End is nil only the start is set.
+type WindowFrame struct {
+	Unit  string
+	Start *FrameBound
+	End   *FrameBound
+}
+
+// FrameBound is the limit of a frame: UNBOUNDED PRECEDING, PRECEDING,
+// CURRENT ROW, FOLLOWING or UNBOUNDED FOLLOWING. PRECEDING and FOLLOWING
+// have an offset.
+type FrameBound struct {
+	Type   string
+	Offset Expr
+}
+
+// WindowDef is a named window of the WINDOW clause.
+type WindowDef struct {
+	Pos  Position
+	Name string
+	Over *OverClause
+}
+
 type GroupConcatExpr struct {
 	Pos         Position
 	Distinct    bool
@@ -658,7 +969,7 @@ const (
 	NOTSET Type = iota
 	ERROR
 	EOF
-	COMMENT // --
+	COMMENT // --, # or /* */
 
 	// Keywords
 	CREATE
@@ -725,7 +1036,7 @@ const (
 
 	IDENT  //  fields, tables...
 	INT    // 12345
-	FLOAT  // 123.45
+	FLOAT  // 123.45, .5 or 1.5e-3
 	STRING // "abc"
 
 	// Operators and delimiters
@@ -758,4 +1069,46 @@ const (
 	COLON     // ;
 	SEMICOLON // ;
 	QUESTION  // ?
+
+	NAMEDPARAM // :name, @name or $name
+
+	// not reserved, only parts of a CASE expression
+	CASE
+	WHEN
+	THEN
+	ELSE
+	END
+
+	WITH // not reserved, only starts a query
+
+	// not reserved, only for window functions
+	OVER
+	WINDOW
+
+	// not reserved, only set operations as UNION
+	INTERSECT
+	EXCEPT
+
+	FULL // not reserved, only a join type
+
+	HEX       // 0x1F
+	BIT       // b'1010' or 0b1010
+	HEXSTRING // X'ABCD'
+
+	ORB    // |  binary OR
+	XOB    // ^  binary XOR
+	NTB    // ~  binary NOT
+	CONCAT // || string concatenation, not OR as in MySQL by default
+
+	ILIKE // not reserved, only a match operator as REGEXP, RLIKE and GLOB
+	NOTILIKE
+	REGEXP
+	NOTREGEXP
+	GLOB
+	NOTGLOB
+
+	ISDISTINCT    // IS DISTINCT FROM
+	ISNOTDISTINCT // IS NOT DISTINCT FROM or <=>
+
+	NSEQ // <=> null-safe equal
 )
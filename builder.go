@@ -58,10 +58,34 @@ func NameExprColumns(e Expr) []*ColumnNameExpr {
 		for _, l := range t.Args {
 			c = append(c, NameExprColumns(l)...)
 		}
+		if t.Over != nil {
+			for _, l := range t.Over.PartitionBy {
+				c = append(c, NameExprColumns(l)...)
+			}
+			for _, l := range t.Over.OrderByPart {
+				c = append(c, NameExprColumns(l.Expr)...)
+			}
+		}
 	case *InExpr:
 		for _, l := range t.Values {
 			c = append(c, NameExprColumns(l)...)
 		}
+	case *CastExpr:
+		c = append(c, NameExprColumns(t.Expr)...)
+	case *EscapeExpr:
+		c = append(c, NameExprColumns(t.Pattern)...)
+		c = append(c, NameExprColumns(t.Escape)...)
+	case *ExistsExpr:
+		if t.Query != nil {
+			c = append(c, selectColumnNames(t.Query)...)
+		}
+	case *CaseExpr:
+		c = append(c, NameExprColumns(t.Value)...)
+		for _, w := range t.Whens {
+			c = append(c, NameExprColumns(w.Cond)...)
+			c = append(c, NameExprColumns(w.Result)...)
+		}
+		c = append(c, NameExprColumns(t.Else)...)
 	}
 
 	return c
@@ -71,6 +95,12 @@ func NameExprColumns(e Expr) []*ColumnNameExpr {
 func selectColumnNames(s *SelectQuery) []*ColumnNameExpr {
 	var c []*ColumnNameExpr
 
+	if s.With != nil {
+		for _, t := range s.With.Tables {
+			c = append(c, selectColumnNames(t.Query)...)
+		}
+	}
+
 	for _, l := range s.Columns {
 		c = append(c, NameExprColumns(l)...)
 	}
@@ -78,9 +108,10 @@ func selectColumnNames(s *SelectQuery) []*ColumnNameExpr {
 	for _, f := range s.From {
 		switch k := f.(type) {
 		case *Table:
-			for _, l := range k.Joins {
-				c = append(c, NameExprColumns(l.On)...)
-			}
+			c = append(c, joinColumnNames(k.Joins)...)
+		case *DerivedTable:
+			c = append(c, selectColumnNames(k.Query)...)
+			c = append(c, joinColumnNames(k.Joins)...)
 		case *ParenExpr:
 			c = append(c, NameExprColumns(k.X)...)
 		}
@@ -102,7 +133,20 @@ func selectColumnNames(s *SelectQuery) []*ColumnNameExpr {
 		c = append(c, NameExprColumns(l.Expr)...)
 	}
 	for _, l := range s.UnionPart {
-		c = append(c, selectColumnNames(l)...)
+		c = append(c, selectColumnNames(l.Query)...)
+	}
+
+	return c
+}
+
+func joinColumnNames(joins []*Join) []*ColumnNameExpr {
+	var c []*ColumnNameExpr
+
+	for _, l := range joins {
+		if l.Subquery != nil {
+			c = append(c, selectColumnNames(l.Subquery.Query)...)
+		}
+		c = append(c, NameExprColumns(l.On)...)
 	}
 
 	return c
@@ -153,14 +197,18 @@ func (q *SelectQuery) Join(code string) error {
 		return fmt.Errorf("Can't add a join to this query (empty FROM)")
 	}
 
-	t, ok := q.From[0].(*Table)
-	if !ok {
+	var joins *[]*Join
+
+	switch t := q.From[0].(type) {
+	case *Table:
+		joins = &t.Joins
+	case *DerivedTable:
+		joins = &t.Joins
+	default:
 		return fmt.Errorf("Can't add a join to this query. From must be a table")
 	}
 
-	switch p.peek().Type {
-	case LEFT, RIGHT, OUTER, JOIN:
-	default:
+	if !p.isJoin() {
 		// if no join type is specified, set a default join
 		k := &Token{Type: JOIN}
 		p.lexer.Tokens = append([]*Token{k}, p.lexer.Tokens...)
@@ -171,7 +219,7 @@ func (q *SelectQuery) Join(code string) error {
 		return err
 	}
 
-	t.Joins = append(t.Joins, js...)
+	*joins = append(*joins, js...)
 	return nil
 }
 
@@ -514,9 +562,7 @@ func (q *DeleteQuery) Join(code string) error {
 		return err
 	}
 
-	switch p.peek().Type {
-	case LEFT, RIGHT, INNER, OUTER, CROSS, JOIN:
-	default:
+	if !p.isJoin() {
 		// if no join type is specified, set a default join
 		k := &Token{Type: JOIN}
 		p.lexer.Tokens = append([]*Token{k}, p.lexer.Tokens...)
@@ -669,9 +715,7 @@ func (q *UpdateQuery) Join(code string) error {
 		return err
 	}
 
-	switch p.peek().Type {
-	case LEFT, RIGHT, INNER, OUTER, CROSS, JOIN:
-	default:
+	if !p.isJoin() {
 		// if no join type is specified, set a default join
 		k := &Token{Type: JOIN}
 		p.lexer.Tokens = append([]*Token{k}, p.lexer.Tokens...)
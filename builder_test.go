@@ -526,8 +526,37 @@ func TestUpdateComplex(t *testing.T) {
 
 	if s != "UPDATE users AS u INNER JOIN b ON u.id = b.idb "+
 		"JOIN c ON u.id = c.idc RIGHT JOIN d ON c.id = d.id "+
-		`SET a = 1, z = 4 WHERE u.status = "a" `+
-		`OR c.status = "c" AND d.id < 100` {
+		`SET a = 1, z = 4 WHERE u.status = 'a' `+
+		`OR c.status = 'c' AND d.id < 100` {
 		t.Fatal(s)
 	}
 }
+
+func TestConcatExists(t *testing.T) {
+	q, err := Select("select * from foo where a = ?", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := q.And("exists (select 1 from bar where bar.id = foo.id and b = ?)", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := q.Or("not exists (select 1 from baz)"); err != nil {
+		t.Fatal(err)
+	}
+
+	s, params, err := toSQL(false, q, q.Params, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "SELECT * FROM foo WHERE a = ? AND EXISTS (SELECT 1 FROM bar WHERE bar.id = foo.id AND b = ?)"+
+		" OR NOT EXISTS (SELECT 1 FROM baz)" {
+		t.Fatal(s)
+	}
+
+	if len(params) != 2 || params[0] != 1 || params[1] != 2 {
+		t.Fatal(params)
+	}
+}
@@ -85,6 +85,64 @@ func TestModifyColumn(t *testing.T) {
 	}
 }
 
+func TestAlterColumnPostgres(t *testing.T) {
+	data := []struct {
+		in  string
+		out string
+	}{
+		{
+			"alter table foo change fizz bar varchar(6) null",
+			`ALTER TABLE foo RENAME COLUMN fizz TO bar`,
+		},
+		{
+			"alter table foo change bar bar varchar(6) null",
+			`ALTER TABLE foo ALTER COLUMN bar TYPE VARCHAR(6), ALTER COLUMN bar DROP NOT NULL, ALTER COLUMN bar DROP DEFAULT`,
+		},
+		{
+			"alter table foo modify bar int not null default 3",
+			`ALTER TABLE foo ALTER COLUMN bar TYPE INTEGER, ALTER COLUMN bar SET NOT NULL, ALTER COLUMN bar SET DEFAULT 3`,
+		},
+	}
+
+	for _, d := range data {
+		q, err := ParseQuery(d.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s, _, err := toSQL(false, q, nil, "", "postgres")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s != d.out {
+			t.Fatal(s)
+		}
+	}
+}
+
+func TestAlterColumnInvalid(t *testing.T) {
+	data := []struct {
+		in     string
+		driver string
+	}{
+		{"alter table foo modify bar varchar(6) null", "sqlite3"},
+		{"alter table foo modify bar int auto_increment", "postgres"},
+		{"alter table foo modify bar int check (bar > 0)", "postgres"},
+	}
+
+	for _, d := range data {
+		q, err := ParseQuery(d.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s, _, err := toSQL(false, q, nil, "", d.driver); err == nil {
+			t.Fatalf("Expected error in %s: %s", d.driver, s)
+		}
+	}
+}
+
 func TestAddUniqueConstraint(t *testing.T) {
 	q, err := ParseQuery("alter table foo add constraint c unique (col1, col2)")
 	if err != nil {
@@ -229,6 +287,22 @@ func TestShowTablesSqlite(t *testing.T) {
 	}
 }
 
+func TestShowTablesPostgres(t *testing.T) {
+	q, err := ParseQuery("show tables")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, nil, "", "postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema()` {
+		t.Fatal(s)
+	}
+}
+
 func TestShowColumns(t *testing.T) {
 	q, err := ParseQuery("show columns from foo")
 	if err != nil {
@@ -508,9 +582,461 @@ func TestParseCreateFks(t *testing.T) {
 	}
 
 	if s != "CREATE TABLE bankaccount ("+
-		"id INTEGER(11) NOT NULL, idClient INTEGER(11) NOT NULL"+
+		"id INTEGER PRIMARY KEY NOT NULL, idClient INTEGER(11) NOT NULL"+
 		", CONSTRAINT u_name UNIQUE (name)"+
 		", CONSTRAINT fk_bankaccountIdClient FOREIGN KEY (idClient) REFERENCES foo_crm_client(id))" {
 		t.Fatal(s)
 	}
 }
+
+func TestShowColumnsPostgres(t *testing.T) {
+	q, err := ParseQuery("show columns from foo.bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, nil, "", "postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "SELECT column_name, data_type, is_nullable, column_default"+
+		" FROM information_schema.columns WHERE table_schema = 'foo' AND table_name = 'bar'"+
+		" ORDER BY ordinal_position" {
+		t.Fatal(s)
+	}
+}
+
+func TestParseCreatePostgres(t *testing.T) {
+	q, err := ParseQuery("create table if not exists cars (id key, name varchar(10), price decimal(12,2) null)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, nil, "", "postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "CREATE TABLE IF NOT EXISTS cars ("+
+		"id SERIAL NOT NULL, "+
+		"name VARCHAR(10) NOT NULL, "+
+		"price NUMERIC(12,2) NULL, "+
+		"PRIMARY KEY(id))" {
+		t.Fatal(s)
+	}
+}
+
+func TestCreateCompositePrimaryKey(t *testing.T) {
+	q, err := ParseQuery("create table tags (idPost int, tag varchar(20), primary key (idPost, tag))")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"mysql": "CREATE TABLE tags (idPost int NOT NULL, tag varchar(20) NOT NULL, PRIMARY KEY(idPost, tag))" +
+			" ENGINE=InnoDb DEFAULT CHARACTER SET = utf8 DEFAULT COLLATE = utf8_general_ci",
+		"sqlite3":  "CREATE TABLE tags (idPost INTEGER NOT NULL, tag VARCHAR(20) NOT NULL COLLATE NOCASE, PRIMARY KEY(idPost, tag))",
+		"postgres": "CREATE TABLE tags (idPost INTEGER NOT NULL, tag VARCHAR(20) NOT NULL, PRIMARY KEY(idPost, tag))",
+	}
+
+	for driver, expected := range tests {
+		s, _, err := toSQL(false, q, nil, "", driver)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s != expected {
+			t.Fatal(s)
+		}
+	}
+}
+
+func TestCreateColumnPrimaryKey(t *testing.T) {
+	q, err := ParseQuery("create table users (code varchar(10) primary key, name text)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, nil, "", "sqlite3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "CREATE TABLE users (code VARCHAR(10) NOT NULL COLLATE NOCASE, name TEXT NOT NULL COLLATE NOCASE, PRIMARY KEY(code))" {
+		t.Fatal(s)
+	}
+
+	q, err = ParseQuery("create table users (num int(11) not null primary key autoincrement, name text)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"mysql": "CREATE TABLE users (num int(11) AUTO_INCREMENT NOT NULL, name text NOT NULL, PRIMARY KEY(num))" +
+			" ENGINE=InnoDb DEFAULT CHARACTER SET = utf8 DEFAULT COLLATE = utf8_general_ci",
+		"sqlite3":  "CREATE TABLE users (num INTEGER PRIMARY KEY NOT NULL, name TEXT NOT NULL COLLATE NOCASE)",
+		"postgres": "CREATE TABLE users (num SERIAL NOT NULL, name TEXT NOT NULL, PRIMARY KEY(num))",
+	}
+
+	for driver, expected := range tests {
+		s, _, err := toSQL(false, q, nil, "", driver)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s != expected {
+			t.Fatal(s)
+		}
+	}
+}
+
+func TestCreatePrimaryKeyInvalid(t *testing.T) {
+	tests := map[string]string{
+		"create table a (id int primary key, b int primary key)":              "mysql",
+		"create table a (id int primary key, b int, primary key (b))":         "mysql",
+		"create table a (id int auto_increment, b int, primary key (b))":      "mysql",
+		"create table a (id varchar(10) auto_increment primary key)":          "mysql",
+		"create table a (id int auto_increment, b int, primary key (id, b))":  "sqlite3",
+		"create table a (id int auto_increment, b int auto_increment, c int)": "mysql",
+	}
+
+	for code, driver := range tests {
+		q, err := ParseQuery(code)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, _, err := toSQL(false, q, nil, "", driver); err == nil {
+			t.Fatal("Expected error: " + code)
+		}
+	}
+
+	if _, err := ParseQuery("create table a (id int, primary key (id), primary key (id))"); err == nil {
+		t.Fatal("Expected error")
+	}
+}
+
+func TestCreateIndexQuery(t *testing.T) {
+	q, err := ParseQuery("create unique index idx_name on users (name(20), email)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, nil, "", "mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "CREATE UNIQUE INDEX idx_name ON users (name(20), email)" {
+		t.Fatal(s)
+	}
+
+	// a unique prefix index can't be written as a full index.
+	if _, _, err := toSQL(false, q, nil, "", "sqlite3"); err == nil {
+		t.Fatal("Expected error")
+	}
+
+	q, err = ParseQuery("create index if not exists idx_name on users (name(20), email)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"sqlite3":  "CREATE INDEX IF NOT EXISTS foo_idx_name ON foo_users (name, email)",
+		"postgres": "CREATE INDEX IF NOT EXISTS idx_name ON foo.users (name, email)",
+	}
+
+	for driver, expected := range tests {
+		s, _, err := toSQL(false, q, nil, "foo", driver)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s != expected {
+			t.Fatal(s)
+		}
+	}
+
+	if _, _, err := toSQL(false, q, nil, "", "mysql"); err == nil {
+		t.Fatal("Expected error")
+	}
+}
+
+func TestDropIndexQuery(t *testing.T) {
+	q, err := ParseQuery("drop index idx_name on users")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"mysql":    "DROP INDEX idx_name ON foo.users",
+		"sqlite3":  "DROP INDEX foo_idx_name",
+		"postgres": "DROP INDEX foo.idx_name",
+	}
+
+	for driver, expected := range tests {
+		s, _, err := toSQL(false, q, nil, "foo", driver)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s != expected {
+			t.Fatal(s)
+		}
+	}
+
+	q, err = ParseQuery("drop index if exists idx_name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, nil, "", "sqlite3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "DROP INDEX IF EXISTS idx_name" {
+		t.Fatal(s)
+	}
+
+	// mysql needs the table.
+	if _, _, err := toSQL(false, q, nil, "", "mysql"); err == nil {
+		t.Fatal("Expected error")
+	}
+}
+
+func TestCreateColumnTypes(t *testing.T) {
+	q, err := ParseQuery(`create table a (
+		id bigint unsigned auto_increment primary key,
+		b smallint,
+		c integer,
+		d float,
+		e double precision,
+		f date,
+		g time,
+		h timestamp(3),
+		i json,
+		j uuid,
+		k longtext,
+		l longblob,
+		m blob,
+		n enum('a', 'b c')
+	)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"mysql": "CREATE TABLE a (id bigint unsigned AUTO_INCREMENT NOT NULL, b smallint NOT NULL, c int NOT NULL," +
+			" d float NOT NULL, e double NOT NULL, f date NOT NULL, g time NOT NULL, h timestamp(3) NOT NULL," +
+			" i json NOT NULL, j char(36) NOT NULL, k longtext NOT NULL, l longblob NOT NULL, m blob NOT NULL," +
+			" n enum('a', 'b c') NOT NULL, PRIMARY KEY(id))" +
+			" ENGINE=InnoDb DEFAULT CHARACTER SET = utf8 DEFAULT COLLATE = utf8_general_ci",
+		"sqlite3": "CREATE TABLE a (id INTEGER PRIMARY KEY NOT NULL, b INTEGER NOT NULL, c INTEGER NOT NULL," +
+			" d REAL NOT NULL, e REAL NOT NULL, f DATE NOT NULL, g TIME NOT NULL, h TIMESTAMP NOT NULL," +
+			" i TEXT NOT NULL, j VARCHAR(36) NOT NULL, k TEXT NOT NULL COLLATE NOCASE, l BLOB NOT NULL, m BLOB NOT NULL," +
+			" n TEXT NOT NULL CHECK (n IN ('a', 'b c')) COLLATE NOCASE)",
+		"postgres": "CREATE TABLE a (id BIGSERIAL NOT NULL, b SMALLINT NOT NULL, c INTEGER NOT NULL," +
+			" d REAL NOT NULL, e DOUBLE PRECISION NOT NULL, f DATE NOT NULL, g TIME NOT NULL, h TIMESTAMP(3) NOT NULL," +
+			" i JSON NOT NULL, j UUID NOT NULL, k TEXT NOT NULL, l BYTEA NOT NULL, m BYTEA NOT NULL," +
+			" n TEXT NOT NULL CHECK (n IN ('a', 'b c')), PRIMARY KEY(id))",
+	}
+
+	for driver, expected := range tests {
+		s, _, err := toSQL(false, q, nil, "", driver)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s != expected {
+			t.Fatal(s)
+		}
+	}
+
+	q, err = ParseQuery("alter table a add b int unsigned")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, nil, "", "postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "ALTER TABLE a ADD COLUMN b BIGINT NOT NULL" {
+		t.Fatal(s)
+	}
+}
+
+func TestCreateColumnTypesInvalid(t *testing.T) {
+	codes := []string{
+		"create table a (b varchar(10) unsigned)",
+		"create table a (b enum())",
+		"create table a (b enum(1, 2))",
+		"select cast(a as enum('x'))",
+	}
+
+	for _, code := range codes {
+		q, err := ParseQuery(code)
+		if err != nil {
+			continue
+		}
+
+		if _, _, err := toSQL(false, q, nil, "", "mysql"); err == nil {
+			t.Fatal("Expected error: " + code)
+		}
+	}
+}
+
+func TestCreateCheckCommentGenerated(t *testing.T) {
+	q, err := ParseQuery(`create table a (
+		price decimal(10,2) not null check (price >= 0) comment 'the price',
+		qty int,
+		total decimal(10,2) generated always as (price * qty) stored,
+		tag varchar(10) as (lower(name)),
+		constraint qty_positive check (qty > 0 and qty < 1000),
+		check (total < 10000)
+	)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"mysql": "CREATE TABLE a (price decimal(10,2) NOT NULL COMMENT 'the price' CHECK (price >= 0), qty int NOT NULL," +
+			" total decimal(10,2) GENERATED ALWAYS AS (price * qty) STORED NOT NULL," +
+			" tag varchar(10) GENERATED ALWAYS AS (LOWER(name)) VIRTUAL NOT NULL," +
+			" CONSTRAINT qty_positive CHECK (qty > 0 AND qty < 1000), CHECK (total < 10000))" +
+			" ENGINE=InnoDb DEFAULT CHARACTER SET = utf8 DEFAULT COLLATE = utf8_general_ci",
+		"sqlite3": "CREATE TABLE a (price REAL(10,2) NOT NULL CHECK (price >= 0), qty INTEGER NOT NULL," +
+			" total REAL(10,2) GENERATED ALWAYS AS (price * qty) STORED NOT NULL," +
+			" tag VARCHAR(10) GENERATED ALWAYS AS (LOWER(name)) VIRTUAL NOT NULL COLLATE NOCASE," +
+			" CONSTRAINT qty_positive CHECK (qty > 0 AND qty < 1000), CHECK (total < 10000))",
+	}
+
+	for driver, expected := range tests {
+		s, _, err := toSQL(false, q, nil, "", driver)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s != expected {
+			t.Fatal(s)
+		}
+	}
+
+	// postgres only has stored generated columns.
+	if _, _, err := toSQL(false, q, nil, "", "postgres"); err == nil {
+		t.Fatal("Expected error")
+	}
+
+	q, err = ParseQuery("create table a (b int, c int generated always as (b + 1) stored comment 'c')")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, nil, "", "postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "CREATE TABLE a (b INTEGER NOT NULL, c INTEGER GENERATED ALWAYS AS (b + 1) STORED NOT NULL)" {
+		t.Fatal(s)
+	}
+
+	codes := []string{
+		"create table a (b int check (b > ?))",
+		"create table a (b int default 1 as (c + 1))",
+		"create table a (b int, check (b > ?))",
+	}
+
+	for _, code := range codes {
+		q, err := ParseQuery(code)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, _, err := toSQL(false, q, []interface{}{1}, "", "mysql"); err == nil {
+			t.Fatal("Expected error: " + code)
+		}
+	}
+}
+
+func TestCreateColumnDefaults(t *testing.T) {
+	q, err := ParseQuery(`create table a (
+		b datetime default current_timestamp on update current_timestamp,
+		c varchar(36) default (uuid()),
+		d int default -1,
+		e text default 'a b',
+		f int null default null
+	)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, nil, "", "mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "CREATE TABLE a (b datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,"+
+		" c varchar(36) NOT NULL DEFAULT (UUID()), d int NOT NULL DEFAULT -1, e text NOT NULL DEFAULT 'a b',"+
+		" f int NULL DEFAULT null)"+
+		" ENGINE=InnoDb DEFAULT CHARACTER SET = utf8 DEFAULT COLLATE = utf8_general_ci" {
+		t.Fatal(s)
+	}
+
+	// sqlite has no ON UPDATE.
+	if _, _, err := toSQL(false, q, nil, "", "sqlite3"); err == nil {
+		t.Fatal("Expected error")
+	}
+
+	q, err = ParseQuery("create table a (b timestamp default current_timestamp, c uuid default (uuid()), d text default ('x' || 'y'))")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err = toSQL(false, q, nil, "", "postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "CREATE TABLE a (b TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, c UUID NOT NULL DEFAULT (gen_random_uuid()),"+
+		" d TEXT NOT NULL DEFAULT ('x' || 'y'))" {
+		t.Fatal(s)
+	}
+}
+
+func TestCreateColumnDefaultsInvalid(t *testing.T) {
+	tests := map[string]string{
+		"create table a (b int default c)":                                  "mysql",
+		"create table a (b int default ?)":                                  "mysql",
+		"create table a (b int default (c + ?))":                            "mysql",
+		"create table a (b datetime on update now(6, 1))":                   "mysql",
+		"create table a (b datetime on update curdate())":                   "mysql",
+		"create table a (b datetime default current_timestamp on update 1)": "mysql",
+		"create table a (b datetime on update current_timestamp)":           "postgres",
+	}
+
+	for code, driver := range tests {
+		q, err := ParseQuery(code)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, _, err := toSQL(false, q, []interface{}{1}, "", driver); err == nil {
+			t.Fatal("Expected error: " + code)
+		}
+	}
+
+	q, err := ParseQuery("create table a (b varchar(36) default (uuid()))")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWriter(q, nil, "", "mysql")
+	w.Dialect = MySQLDialect{Version: "5.7"}
+	if _, _, err := w.Write(); err == nil {
+		t.Fatal("Expected error")
+	}
+}
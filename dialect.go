@@ -0,0 +1,293 @@
+package goql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A Dialect translates the parts of a query that are different
+// in each database.
+//
+// The writer validates all identifiers and sizes before passing them
+// to a dialect and expressions are passed already written so a dialect
+// only needs to compose the final SQL.
+//
+// To support a new database a dialect can embed one of the builtin
+// dialects and override only what is different. Then register it with
+// RegisterDialect to use it as a driver name in NewWriter and ToSql.
+type Dialect interface {
+	// QuoteIdent quotes a valid identifier.
+	QuoteIdent(s string) string
+
+	// Placeholder returns the symbol of the parameter n. It starts at 1
+	// and only counts the parameters that are written in the query.
+	Placeholder(n int) string
+
+	// BoolLiteral returns a boolean constant.
+	BoolLiteral(v bool) string
+
+	// StringLiteral returns a quoted string constant.
+	StringLiteral(s string) string
+
+	// BinaryLiteral returns a HEX (0x1F), BIT (b'1010') or HEXSTRING
+	// (X'ABCD') constant. digits are already validated.
+	BinaryLiteral(kind Type, digits string) (string, error)
+
+	// ColumnType returns the type of a column in a CREATE or ALTER
+	// query including the size if it has one.
+	ColumnType(c *CreateColumn) (string, error)
+
+	// CastType returns the type of a CAST including the size if it has one.
+	CastType(c *CreateColumn) (string, error)
+
+	// AutoIncrement returns the attribute that is written after the type
+	// of a key column and if the table must declare the PRIMARY KEY.
+	AutoIncrement() (attr string, primaryKey bool)
+
+	// ColumnCollation returns the collation of a column or "" for the default.
+	ColumnCollation(c *CreateColumn) string
+
+	// TableOptions returns what is written after the columns of CREATE TABLE.
+	TableOptions() string
+
+	// Limit returns the LIMIT clause. offset is "" if it is not set.
+	Limit(offset, rowCount string) string
+
+	// Func translates a function call. If ok is false the function is
+	// written as it is.
+	Func(name string, args []string) (sql string, ok bool, err error)
+
+	// ConcatOperator returns the string concatenation operator or "" if
+	// the database uses the CONCAT function.
+	ConcatOperator() string
+
+	// XorOperator returns the binary XOR operator or "" if the database
+	// has none and it is emulated with | and &.
+	XorOperator() string
+
+	// Match writes a LIKE, ILIKE, REGEXP or GLOB comparison. not is
+	// true if it is negated and escape is "" if there is no ESCAPE.
+	Match(op Type, not bool, expr, pattern, escape string) (string, error)
+
+	// IsDistinct writes a comparison in which nulls are equal values.
+	// If not is true it is IS NOT DISTINCT FROM.
+	IsDistinct(not bool, left, right string) string
+
+	// GroupConcat writes a GROUP_CONCAT aggregation. orderBy is "" or
+	// the complete ORDER BY clause.
+	GroupConcat(distinct bool, exprs []string, orderBy, separator string) (string, error)
+
+	// Excluded returns the reference to the value that an upsert tried
+	// to insert in a column.
+	Excluded(column string) string
+
+	// OnConflict returns the upsert clause of an INSERT. columns is the
+	// conflict target and update the assignments already written.
+	OnConflict(columns, update []string, doNothing bool) (string, error)
+
+	// Show returns the query that emulates SHOW DATABASES, TABLES,
+	// COLUMNS or INDEX. The table name has the namespace already applied
+	// and ident quotes an identifier as configured in the writer.
+	Show(typ, database, table string, ident func(string) string) (string, error)
+
+	// Supports returns if the database supports a feature.
+	Supports(f Feature) bool
+}
+
+// A Feature is something that not every database supports.
+type Feature int
+
+const (
+	// Databases is the support of database.table names. If not
+	// supported the database is simulated prefixing the tables.
+	Databases Feature = iota
+
+	// CreateDatabase is the support of CREATE DATABASE.
+	CreateDatabase
+
+	// CreateDatabaseIfNotExists is the support of IF NOT EXISTS in CREATE DATABASE.
+	CreateDatabaseIfNotExists
+
+	// UpdateJoin is the support of joins and aliases in UPDATE and DELETE.
+	UpdateJoin
+
+	// CTE is the support of common table expressions (WITH) in SELECT.
+	CTE
+
+	// CTEUpdate is the support of common table expressions in UPDATE and DELETE.
+	CTEUpdate
+
+	// WindowFunctions is the support of OVER and the WINDOW clause.
+	WindowFunctions
+
+	// IntersectExcept is the support of INTERSECT and EXCEPT. If not
+	// supported they are emulated grouping a UNION ALL.
+	IntersectExcept
+
+	// RightJoin is the support of RIGHT JOIN. If not supported the first
+	// join of a select is emulated with a LEFT JOIN.
+	RightJoin
+
+	// FullJoin is the support of FULL JOIN. If not supported the first
+	// join of a select is emulated with a LEFT JOIN and a UNION ALL.
+	FullJoin
+
+	// TableIndexNames is if index names belong to a table and DROP INDEX
+	// needs ON table. If not supported the names are global and they are
+	// prefixed with the namespace and the database as the tables.
+	TableIndexNames
+
+	// PrefixIndexes is the support of a length in the columns of an index.
+	PrefixIndexes
+
+	// IndexIfExists is the support of IF NOT EXISTS in CREATE INDEX
+	// and IF EXISTS in DROP INDEX.
+	IndexIfExists
+
+	// Enums is the support of ENUM columns. If not supported they are
+	// text columns with a CHECK of the values.
+	Enums
+
+	// ColumnComments is the support of COMMENT in a column. If not
+	// supported the comment is omitted.
+	ColumnComments
+
+	// GeneratedColumns is the support of GENERATED ALWAYS AS columns.
+	GeneratedColumns
+
+	// VirtualColumns is the support of generated columns that are not STORED.
+	VirtualColumns
+
+	// ExpressionDefaults is the support of expressions as default values.
+	// Constants and CURRENT_TIMESTAMP are supported by all databases.
+	ExpressionDefaults
+
+	// OnUpdateTimestamp is the support of ON UPDATE CURRENT_TIMESTAMP.
+	OnUpdateTimestamp
+
+	// UpdateLimit is the support of LIMIT in UPDATE and DELETE. SQLite
+	// only supports it if it is compiled with SQLITE_ENABLE_UPDATE_DELETE_LIMIT.
+	UpdateLimit
+
+	// ChangeColumn is the support of CHANGE and MODIFY in ALTER TABLE. If
+	// not supported CHANGE is written as RENAME COLUMN, that can't modify
+	// the column in the same query, and MODIFY as ALTER COLUMN.
+	ChangeColumn
+
+	// AlterColumn is the support of ALTER COLUMN to change the type, the
+	// nullability and the default value of a column.
+	AlterColumn
+)
+
+var (
+	dialectsMu sync.RWMutex
+	dialects   = map[string]Dialect{
+		"mysql":    MySQLDialect{},
+		"sqlite3":  SQLiteDialect{},
+		"postgres": PostgresDialect{},
+	}
+)
+
+// RegisterDialect makes a dialect available by the driver name.
+// If a dialect is already registered with that name it is replaced.
+func RegisterDialect(driver string, d Dialect) {
+	dialectsMu.Lock()
+	dialects[driver] = d
+	dialectsMu.Unlock()
+}
+
+// versionAtLeast returns true if version is equal or greater than min.
+// An empty version is the latest one.
+func versionAtLeast(version, min string) bool {
+	if version == "" {
+		return true
+	}
+
+	a := strings.Split(version, ".")
+	b := strings.Split(min, ".")
+
+	for i := range b {
+		var x, y int
+		if i < len(a) {
+			x, _ = strconv.Atoi(a[i])
+		}
+		y, _ = strconv.Atoi(b[i])
+
+		if x != y {
+			return x > y
+		}
+	}
+
+	return true
+}
+
+// quoteString quotes a string constant with single quotes, the only ones
+// that all databases read as strings. If backslash is true backslashes
+// are escaped because the database reads them as escape sequences.
+func quoteString(s string, backslash bool) string {
+	var b strings.Builder
+
+	b.WriteByte('\'')
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\'':
+			b.WriteString("''")
+		case c == '\\' && backslash:
+			b.WriteString("\\\\")
+		case c == 0 && backslash:
+			b.WriteString("\\0")
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// binaryDecimal writes a HEX or BIT constant as a decimal integer.
+func binaryDecimal(kind Type, digits string) (string, error) {
+	base := 16
+	if kind == BIT {
+		base = 2
+	}
+
+	v, err := strconv.ParseUint(digits, base, 64)
+	if err != nil {
+		return "", fmt.Errorf("Invalid number %s", digits)
+	}
+
+	return strconv.FormatUint(v, 10), nil
+}
+
+// match writes a comparison with a match operator.
+func match(operator string, not bool, expr, pattern, escape string) string {
+	s := expr + " "
+	if not {
+		s += "NOT "
+	}
+
+	s += operator + " " + pattern
+
+	if escape != "" {
+		s += " ESCAPE " + escape
+	}
+	return s
+}
+
+// lowerMatch emulates ILIKE with LIKE comparing in lowercase.
+func lowerMatch(not bool, expr, pattern, escape string) string {
+	return match("LIKE", not, "LOWER("+expr+")", "LOWER("+pattern+")", escape)
+}
+
+// GetDialect returns the dialect registered for a driver.
+func GetDialect(driver string) (Dialect, bool) {
+	dialectsMu.RLock()
+	d, ok := dialects[driver]
+	dialectsMu.RUnlock()
+	return d, ok
+}
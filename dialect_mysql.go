@@ -0,0 +1,302 @@
+package goql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MySQLDialect writes queries for MySQL.
+type MySQLDialect struct {
+	// Version is the version of the server, for example "5.7". The
+	// features that it doesn't support are emulated or return an error.
+	// If it is empty it is the latest version.
+	Version string
+}
+
+func (MySQLDialect) QuoteIdent(s string) string {
+	return "`" + s + "`"
+}
+
+func (MySQLDialect) Placeholder(n int) string {
+	return "?"
+}
+
+func (MySQLDialect) BoolLiteral(v bool) string {
+	if v {
+		return "true"
+	}
+	return "false"
+}
+
+// StringLiteral escapes backslashes because they are escape sequences
+// with the default sql_mode. With NO_BACKSLASH_ESCAPES they would be
+// written twice but it is still safe because quotes are doubled.
+func (MySQLDialect) StringLiteral(s string) string {
+	return quoteString(s, true)
+}
+
+func (MySQLDialect) BinaryLiteral(kind Type, digits string) (string, error) {
+	switch kind {
+	case HEX:
+		return "0x" + digits, nil
+	case BIT:
+		return "b'" + digits + "'", nil
+	case HEXSTRING:
+		return "X'" + digits + "'", nil
+	default:
+		return "", fmt.Errorf("Invalid constant type: %v", kind)
+	}
+}
+
+func (d MySQLDialect) ColumnType(c *CreateColumn) (string, error) {
+	var t string
+
+	switch c.Type {
+	case Int:
+		t = "int"
+	case BigInt:
+		t = "bigint"
+	case SmallInt:
+		t = "smallint"
+	case Decimal:
+		t = "decimal"
+	case Float:
+		t = "float"
+	case Double:
+		t = "double"
+	case Char:
+		t = "char"
+	case Varchar:
+		t = "varchar"
+	case Text:
+		t = "text"
+	case MediumText:
+		t = "mediumtext"
+	case LongText:
+		t = "longtext"
+	case Bool:
+		t = "bool"
+	case Blob:
+		t = "blob"
+	case LongBlob:
+		t = "longblob"
+	case DatTime:
+		t = "datetime"
+	case Date:
+		return "date", nil
+	case Time:
+		t = "time"
+	case Timestamp:
+		t = "timestamp"
+	case JSON:
+		return "json", nil
+	case UUID:
+		// mysql has no uuid type. Stored as text to be readable.
+		return "char(36)", nil
+	case Enum:
+		values := make([]string, len(c.Values))
+		for i, v := range c.Values {
+			values[i] = d.StringLiteral(v)
+		}
+		return "enum(" + strings.Join(values, ", ") + ")", nil
+	default:
+		return "", fmt.Errorf("Invalid column type %d", c.Type)
+	}
+
+	t += columnSize(c)
+
+	if c.Unsigned {
+		t += " unsigned"
+	}
+
+	return t, nil
+}
+
+func (d MySQLDialect) CastType(c *CreateColumn) (string, error) {
+	switch c.Type {
+	case Int, BigInt, SmallInt, Bool:
+		if c.Unsigned {
+			return "UNSIGNED", nil
+		}
+		return "SIGNED", nil
+	case Decimal:
+		return "DECIMAL" + columnSize(c), nil
+	case Float, Double:
+		if !versionAtLeast(d.Version, "8.0.17") {
+			return "DECIMAL" + columnSize(c), nil
+		}
+		if c.Type == Float {
+			return "FLOAT", nil
+		}
+		return "DOUBLE", nil
+	case Char, Varchar, Text, MediumText, LongText:
+		return "CHAR" + columnSize(c), nil
+	case UUID:
+		return "CHAR(36)", nil
+	case Blob, LongBlob:
+		return "BINARY" + columnSize(c), nil
+	case DatTime, Timestamp:
+		return "DATETIME", nil
+	case Date:
+		return "DATE", nil
+	case Time:
+		return "TIME", nil
+	case JSON:
+		return "JSON", nil
+	default:
+		return "", fmt.Errorf("Invalid cast type %d", c.Type)
+	}
+}
+
+func (MySQLDialect) AutoIncrement() (string, bool) {
+	return "AUTO_INCREMENT", true
+}
+
+func (MySQLDialect) ColumnCollation(c *CreateColumn) string {
+	return ""
+}
+
+func (MySQLDialect) TableOptions() string {
+	return "ENGINE=InnoDb" +
+		" DEFAULT CHARACTER SET = utf8" +
+		" DEFAULT COLLATE = utf8_general_ci"
+}
+
+func (MySQLDialect) Limit(offset, rowCount string) string {
+	if offset != "" {
+		return "LIMIT " + offset + ", " + rowCount
+	}
+	return "LIMIT " + rowCount
+}
+
+func (MySQLDialect) Func(name string, args []string) (string, bool, error) {
+	return "", false, nil
+}
+
+// ConcatOperator is empty because by default || is OR in MySQL.
+func (MySQLDialect) ConcatOperator() string {
+	return ""
+}
+
+func (MySQLDialect) XorOperator() string {
+	return "^"
+}
+
+// Match emulates ILIKE with LOWER. MySQL has no GLOB.
+func (MySQLDialect) Match(op Type, not bool, expr, pattern, escape string) (string, error) {
+	switch op {
+	case LIKE:
+		return match("LIKE", not, expr, pattern, escape), nil
+	case ILIKE:
+		return lowerMatch(not, expr, pattern, escape), nil
+	case REGEXP:
+		return match("REGEXP", not, expr, pattern, ""), nil
+	default:
+		return "", fmt.Errorf("%v is not supported by MySQL", op)
+	}
+}
+
+// IsDistinct uses the null-safe equal operator <=>.
+func (MySQLDialect) IsDistinct(not bool, left, right string) string {
+	if not {
+		return left + " <=> " + right
+	}
+	return "NOT (" + left + " <=> " + right + ")"
+}
+
+func (MySQLDialect) GroupConcat(distinct bool, exprs []string, orderBy, separator string) (string, error) {
+	var b strings.Builder
+
+	b.WriteString("GROUP_CONCAT(")
+
+	if distinct {
+		b.WriteString("DISTINCT ")
+	}
+
+	b.WriteString(strings.Join(exprs, ","))
+
+	if orderBy != "" {
+		b.WriteRune(' ')
+		b.WriteString(orderBy)
+	}
+
+	if separator != "" {
+		b.WriteString(" SEPARATOR '")
+		b.WriteString(separator)
+		b.WriteRune('\'')
+	}
+
+	b.WriteRune(')')
+	return b.String(), nil
+}
+
+func (MySQLDialect) Excluded(column string) string {
+	return "VALUES(" + column + ")"
+}
+
+func (MySQLDialect) OnConflict(columns, update []string, doNothing bool) (string, error) {
+	if doNothing {
+		// MySQL has no DO NOTHING. Assigning a column to itself
+		// leaves the row as it is.
+		if len(columns) == 0 {
+			return "", fmt.Errorf("ON CONFLICT DO NOTHING requires the conflict columns in mysql")
+		}
+		return "ON DUPLICATE KEY UPDATE " + columns[0] + " = " + columns[0], nil
+	}
+
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(update, ", "), nil
+}
+
+func (MySQLDialect) Show(typ, database, table string, ident func(string) string) (string, error) {
+	switch typ {
+	case "databases":
+		return "SHOW DATABASES", nil
+	case "tables":
+		if database != "" {
+			return "SHOW TABLES FROM " + ident(database), nil
+		}
+		return "SHOW TABLES", nil
+	case "columns":
+		return "SHOW COLUMNS FROM " + qualifiedName(database, table, ident), nil
+	case "index":
+		return "SHOW INDEX FROM " + qualifiedName(database, table, ident), nil
+	default:
+		return "", fmt.Errorf("Invalid identifier %s", typ)
+	}
+}
+
+func (d MySQLDialect) Supports(f Feature) bool {
+	switch f {
+	case CTE, CTEUpdate, WindowFunctions:
+		return versionAtLeast(d.Version, "8.0")
+	case IntersectExcept:
+		return versionAtLeast(d.Version, "8.0.31")
+	case GeneratedColumns, VirtualColumns:
+		return versionAtLeast(d.Version, "5.7")
+	case ExpressionDefaults:
+		return versionAtLeast(d.Version, "8.0.13")
+	case FullJoin, IndexIfExists, AlterColumn:
+		return false
+	}
+	return true
+}
+
+// columnSize returns the size part of a column type if it has one.
+func columnSize(c *CreateColumn) string {
+	if c.Size == "" {
+		return ""
+	}
+
+	if c.Decimals != "" {
+		return "(" + c.Size + "," + c.Decimals + ")"
+	}
+
+	return "(" + c.Size + ")"
+}
+
+func qualifiedName(database, table string, ident func(string) string) string {
+	if database != "" {
+		return ident(database) + "." + ident(table)
+	}
+	return ident(table)
+}
@@ -0,0 +1,307 @@
+package goql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PostgresDialect writes queries for PostgreSQL. Databases are
+// mapped to schemas.
+type PostgresDialect struct{}
+
+func (PostgresDialect) QuoteIdent(s string) string {
+	return `"` + s + `"`
+}
+
+func (PostgresDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (PostgresDialect) BoolLiteral(v bool) string {
+	if v {
+		return "true"
+	}
+	return "false"
+}
+
+// StringLiteral uses single quotes because double quotes are identifiers in postgres.
+// Backslashes are not escaped because standard_conforming_strings is
+// on by default.
+func (PostgresDialect) StringLiteral(s string) string {
+	return quoteString(s, false)
+}
+
+// BinaryLiteral writes numbers as decimals and strings as bytea because
+// in postgres X'ABCD' and b'1010' are bit strings.
+func (PostgresDialect) BinaryLiteral(kind Type, digits string) (string, error) {
+	switch kind {
+	case HEX, BIT:
+		if digits == "" {
+			// b'' is an empty binary string.
+			return "DECODE('', 'hex')", nil
+		}
+		return binaryDecimal(kind, digits)
+	case HEXSTRING:
+		return "DECODE('" + digits + "', 'hex')", nil
+	default:
+		return "", fmt.Errorf("Invalid constant type: %v", kind)
+	}
+}
+
+func (PostgresDialect) ColumnType(c *CreateColumn) (string, error) {
+	if c.Key || c.AutoIncrement {
+		// serial is an int with a sequence as the default value.
+		switch c.Type {
+		case BigInt:
+			return "BIGSERIAL", nil
+		case SmallInt:
+			return "SMALLSERIAL", nil
+		}
+		return "SERIAL", nil
+	}
+
+	var t string
+
+	// postgres doesn't allow a display width for integers and
+	// has no unsigned types so they use the next bigger one.
+	switch c.Type {
+	case Int:
+		if c.Unsigned {
+			return "BIGINT", nil
+		}
+		return "INTEGER", nil
+	case BigInt:
+		if c.Unsigned {
+			return "NUMERIC(20)", nil
+		}
+		return "BIGINT", nil
+	case SmallInt:
+		if c.Unsigned {
+			return "INTEGER", nil
+		}
+		return "SMALLINT", nil
+	case Decimal:
+		t = "NUMERIC"
+	case Float:
+		return "REAL", nil
+	case Double:
+		return "DOUBLE PRECISION", nil
+	case Char:
+		t = "CHAR"
+	case Varchar:
+		t = "VARCHAR"
+	case Text, MediumText, LongText, Enum:
+		t = "TEXT"
+	case Bool:
+		t = "BOOLEAN"
+	case Blob, LongBlob:
+		t = "BYTEA"
+	case DatTime, Timestamp:
+		t = "TIMESTAMP"
+	case Date:
+		return "DATE", nil
+	case Time:
+		t = "TIME"
+	case JSON:
+		return "JSON", nil
+	case UUID:
+		return "UUID", nil
+	default:
+		return "", fmt.Errorf("Invalid column type %d", c.Type)
+	}
+
+	return t + columnSize(c), nil
+}
+
+// CastType casts an unsigned int to BIGINT because postgres has no
+// unsigned types.
+func (PostgresDialect) CastType(c *CreateColumn) (string, error) {
+	switch c.Type {
+	case Int:
+		if c.Unsigned {
+			return "BIGINT", nil
+		}
+		return "INTEGER", nil
+	case BigInt:
+		return "BIGINT", nil
+	case SmallInt:
+		return "SMALLINT", nil
+	case Decimal:
+		return "NUMERIC" + columnSize(c), nil
+	case Float:
+		return "REAL", nil
+	case Double:
+		return "DOUBLE PRECISION", nil
+	case Char, Varchar:
+		return "VARCHAR" + columnSize(c), nil
+	case Text, MediumText, LongText:
+		return "TEXT", nil
+	case Bool:
+		return "BOOLEAN", nil
+	case Blob, LongBlob:
+		return "BYTEA", nil
+	case DatTime, Timestamp:
+		return "TIMESTAMP", nil
+	case Date:
+		return "DATE", nil
+	case Time:
+		return "TIME", nil
+	case JSON:
+		return "JSON", nil
+	case UUID:
+		return "UUID", nil
+	default:
+		return "", fmt.Errorf("Invalid cast type %d", c.Type)
+	}
+}
+
+func (PostgresDialect) AutoIncrement() (string, bool) {
+	return "", true
+}
+
+func (PostgresDialect) ColumnCollation(c *CreateColumn) string {
+	return ""
+}
+
+func (PostgresDialect) TableOptions() string {
+	return ""
+}
+
+// Limit writes the OFFSET after the LIMIT because postgres doesn't
+// support "LIMIT offset, count".
+func (PostgresDialect) Limit(offset, rowCount string) string {
+	if offset != "" {
+		return "LIMIT " + rowCount + " OFFSET " + offset
+	}
+	return "LIMIT " + rowCount
+}
+
+func (PostgresDialect) Func(name string, args []string) (string, bool, error) {
+	switch name {
+	case "UTC_TIMESTAMP":
+		if len(args) > 0 {
+			return "", false, fmt.Errorf("Expected 0 args")
+		}
+		return "(NOW() AT TIME ZONE 'UTC')", true, nil
+
+	case "UUID":
+		if len(args) > 0 {
+			return "", false, fmt.Errorf("Expected 0 args")
+		}
+		return "gen_random_uuid()", true, nil
+	}
+
+	return "", false, nil
+}
+
+func (PostgresDialect) ConcatOperator() string {
+	return "||"
+}
+
+// XorOperator is # because ^ is the exponentiation in postgres.
+func (PostgresDialect) XorOperator() string {
+	return "#"
+}
+
+// Match writes REGEXP as ~. Postgres has no GLOB.
+func (PostgresDialect) Match(op Type, not bool, expr, pattern, escape string) (string, error) {
+	switch op {
+	case LIKE:
+		return match("LIKE", not, expr, pattern, escape), nil
+	case ILIKE:
+		return match("ILIKE", not, expr, pattern, escape), nil
+	case REGEXP:
+		if not {
+			return expr + " !~ " + pattern, nil
+		}
+		return expr + " ~ " + pattern, nil
+	default:
+		return "", fmt.Errorf("%v is not supported by PostgreSQL", op)
+	}
+}
+
+func (PostgresDialect) IsDistinct(not bool, left, right string) string {
+	if not {
+		return left + " IS NOT DISTINCT FROM " + right
+	}
+	return left + " IS DISTINCT FROM " + right
+}
+
+// GroupConcat uses string_agg. It only accepts one text expression so
+// multiple expressions are concatenated.
+func (PostgresDialect) GroupConcat(distinct bool, exprs []string, orderBy, separator string) (string, error) {
+	var b strings.Builder
+
+	b.WriteString("STRING_AGG(")
+
+	if distinct {
+		b.WriteString("DISTINCT ")
+	}
+
+	if len(exprs) == 1 {
+		b.WriteString("CAST(" + exprs[0] + " AS TEXT)")
+	} else {
+		b.WriteString("CONCAT(" + strings.Join(exprs, ", ") + ")")
+	}
+
+	if separator == "" {
+		separator = ","
+	}
+
+	b.WriteString(", '" + separator + "'")
+
+	if orderBy != "" {
+		b.WriteRune(' ')
+		b.WriteString(orderBy)
+	}
+
+	b.WriteRune(')')
+	return b.String(), nil
+}
+
+func (PostgresDialect) Excluded(column string) string {
+	return "excluded." + column
+}
+
+// OnConflict requires the conflict columns to update because postgres
+// can't take them from the keys of the table as ON DUPLICATE KEY UPDATE.
+func (PostgresDialect) OnConflict(columns, update []string, doNothing bool) (string, error) {
+	if len(columns) == 0 && !doNothing {
+		return "", fmt.Errorf("ON DUPLICATE KEY UPDATE requires the conflict columns in postgres, use ON CONFLICT (columns) DO UPDATE")
+	}
+	return onConflict(columns, update, doNothing), nil
+}
+
+func (PostgresDialect) Show(typ, database, table string, ident func(string) string) (string, error) {
+	schema := "current_schema()"
+	if database != "" {
+		schema = "'" + database + "'"
+	}
+
+	switch typ {
+	case "databases":
+		return "SELECT schema_name FROM information_schema.schemata", nil
+	case "tables":
+		return "SELECT table_name FROM information_schema.tables WHERE table_schema = " + schema, nil
+	case "columns":
+		return "SELECT column_name, data_type, is_nullable, column_default" +
+			" FROM information_schema.columns" +
+			" WHERE table_schema = " + schema + " AND table_name = '" + table + "'" +
+			" ORDER BY ordinal_position", nil
+	case "index":
+		return "SELECT indexname, indexdef FROM pg_indexes" +
+			" WHERE schemaname = " + schema + " AND tablename = '" + table + "'", nil
+	default:
+		return "", fmt.Errorf("Invalid identifier %s", typ)
+	}
+}
+
+func (PostgresDialect) Supports(f Feature) bool {
+	switch f {
+	case CreateDatabaseIfNotExists, UpdateJoin, UpdateLimit, ChangeColumn, TableIndexNames,
+		PrefixIndexes, Enums, ColumnComments, VirtualColumns, OnUpdateTimestamp:
+		return false
+	}
+	return true
+}
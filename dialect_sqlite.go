@@ -0,0 +1,263 @@
+package goql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SQLiteDialect writes queries for SQLite.
+type SQLiteDialect struct {
+	// Version is the version of the library, for example "3.31". The
+	// features that it doesn't support are emulated or return an error.
+	// If it is empty it is the latest version.
+	Version string
+}
+
+func (SQLiteDialect) QuoteIdent(s string) string {
+	return "`" + s + "`"
+}
+
+func (SQLiteDialect) Placeholder(n int) string {
+	return "?"
+}
+
+func (SQLiteDialect) BoolLiteral(v bool) string {
+	if v {
+		return "1"
+	}
+	return "0"
+}
+
+// StringLiteral uses single quotes because double quotes are identifiers
+// if there is a column with that name.
+func (SQLiteDialect) StringLiteral(s string) string {
+	return quoteString(s, false)
+}
+
+// BinaryLiteral writes numbers as decimals because SQLite has no b'1010'
+// and reads a 0x constant above the max int64 as a negative number.
+func (SQLiteDialect) BinaryLiteral(kind Type, digits string) (string, error) {
+	switch kind {
+	case HEX, BIT:
+		if digits == "" {
+			// b'' is an empty binary string.
+			return "X''", nil
+		}
+		return binaryDecimal(kind, digits)
+	case HEXSTRING:
+		return "X'" + digits + "'", nil
+	default:
+		return "", fmt.Errorf("Invalid constant type: %v", kind)
+	}
+}
+
+func (SQLiteDialect) ColumnType(c *CreateColumn) (string, error) {
+	var t string
+
+	switch c.Type {
+	case Int, BigInt, SmallInt:
+		// only an INTEGER PRIMARY KEY without size is an alias of the rowid.
+		if c.Key || c.AutoIncrement {
+			return "INTEGER", nil
+		}
+		t = "INTEGER"
+	case Decimal, Float, Double:
+		t = "REAL"
+	case Char, Varchar:
+		t = "VARCHAR"
+	case Text, MediumText, LongText, JSON, Enum:
+		t = "TEXT"
+	case UUID:
+		return "VARCHAR(36)", nil
+	case Bool:
+		t = "BOOLEAN"
+	case Blob, LongBlob:
+		return "BLOB", nil
+	case DatTime:
+		t = "DATETIME"
+	case Date:
+		return "DATE", nil
+	case Time:
+		return "TIME", nil
+	case Timestamp:
+		return "TIMESTAMP", nil
+	default:
+		return "", fmt.Errorf("Invalid column type %d", c.Type)
+	}
+
+	return t + columnSize(c), nil
+}
+
+// CastType ignores the size because sqlite doesn't limit the length.
+// Dates are stored as text.
+func (SQLiteDialect) CastType(c *CreateColumn) (string, error) {
+	switch c.Type {
+	case Int, BigInt, SmallInt, Bool:
+		return "INTEGER", nil
+	case Decimal, Float, Double:
+		return "REAL", nil
+	case Char, Varchar, Text, MediumText, LongText, JSON, UUID,
+		DatTime, Date, Time, Timestamp:
+		return "TEXT", nil
+	case Blob, LongBlob:
+		return "BLOB", nil
+	default:
+		return "", fmt.Errorf("Invalid cast type %d", c.Type)
+	}
+}
+
+// AutoIncrement returns PRIMARY KEY because in sqlite an INTEGER PRIMARY KEY
+// column is an alias of the rowid.
+func (SQLiteDialect) AutoIncrement() (string, bool) {
+	return "PRIMARY KEY", false
+}
+
+func (SQLiteDialect) ColumnCollation(c *CreateColumn) string {
+	switch c.Type {
+	case Char, Varchar, Text, MediumText, LongText, Enum:
+		return "NOCASE"
+	}
+	return ""
+}
+
+func (SQLiteDialect) TableOptions() string {
+	return ""
+}
+
+func (SQLiteDialect) Limit(offset, rowCount string) string {
+	if offset != "" {
+		return "LIMIT " + offset + ", " + rowCount
+	}
+	return "LIMIT " + rowCount
+}
+
+func (SQLiteDialect) Func(name string, args []string) (string, bool, error) {
+	switch name {
+	case "CONCAT", "CONCAT_WS": // _ws is not the same but use whats available.
+		return strings.Join(args, " || "), true, nil
+
+	case "UTC_TIMESTAMP":
+		if len(args) > 0 {
+			return "", false, fmt.Errorf("Expected 0 args")
+		}
+		return "datetime('now')", true, nil
+	}
+
+	return "", false, nil
+}
+
+func (SQLiteDialect) ConcatOperator() string {
+	return "||"
+}
+
+func (SQLiteDialect) XorOperator() string {
+	return ""
+}
+
+// Match emulates ILIKE with LOWER. SQLite translates REGEXP to a call
+// to the function regexp(pattern, text) that the application must
+// register in the connection. For example with mattn/go-sqlite3:
+//
+//	sql.Register("sqlite3_regexp", &sqlite3.SQLiteDriver{
+//		ConnectHook: func(c *sqlite3.SQLiteConn) error {
+//			return c.RegisterFunc("regexp", func(re, s string) (bool, error) {
+//				return regexp.MatchString(re, s)
+//			}, true)
+//		},
+//	})
+func (SQLiteDialect) Match(op Type, not bool, expr, pattern, escape string) (string, error) {
+	switch op {
+	case LIKE:
+		return match("LIKE", not, expr, pattern, escape), nil
+	case ILIKE:
+		return lowerMatch(not, expr, pattern, escape), nil
+	case REGEXP:
+		return match("REGEXP", not, expr, pattern, ""), nil
+	case GLOB:
+		return match("GLOB", not, expr, pattern, ""), nil
+	default:
+		return "", fmt.Errorf("Invalid match operator %v", op)
+	}
+}
+
+// IsDistinct uses IS and IS NOT that in SQLite compare any value.
+func (SQLiteDialect) IsDistinct(not bool, left, right string) string {
+	if not {
+		return left + " IS " + right
+	}
+	return left + " IS NOT " + right
+}
+
+func (SQLiteDialect) GroupConcat(distinct bool, exprs []string, orderBy, separator string) (string, error) {
+	return "GROUP_CONCAT(" + strings.Join(exprs, ",") + ")", nil
+}
+
+func (SQLiteDialect) Excluded(column string) string {
+	return "excluded." + column
+}
+
+// OnConflict writes DO UPDATE without the conflict columns, as a MySQL
+// ON DUPLICATE KEY UPDATE, from sqlite 3.35.
+func (d SQLiteDialect) OnConflict(columns, update []string, doNothing bool) (string, error) {
+	if len(columns) == 0 && !doNothing && !versionAtLeast(d.Version, "3.35") {
+		return "", fmt.Errorf("ON CONFLICT DO UPDATE requires the conflict columns before sqlite3 3.35")
+	}
+	return onConflict(columns, update, doNothing), nil
+}
+
+// onConflict writes the ON CONFLICT clause of sqlite and postgres.
+func onConflict(columns, update []string, doNothing bool) string {
+	s := "ON CONFLICT"
+
+	if len(columns) > 0 {
+		s += " (" + strings.Join(columns, ", ") + ")"
+	}
+
+	if doNothing {
+		return s + " DO NOTHING"
+	}
+
+	return s + " DO UPDATE SET " + strings.Join(update, ", ")
+}
+
+func (SQLiteDialect) Show(typ, database, table string, ident func(string) string) (string, error) {
+	switch typ {
+	case "databases":
+		return "", fmt.Errorf("Sqlite doesn't support 'SHOW DATABASES'")
+	case "tables":
+		s := `SELECT name FROM sqlite_master WHERE type = "table"`
+		if database != "" {
+			s += ` AND name like "` + database + `%"`
+		}
+		return s, nil
+	case "columns":
+		return "PRAGMA table_info(" + ident(sqliteTableName(database, table)) + ")", nil
+	case "index":
+		return "PRAGMA index_list(" + ident(sqliteTableName(database, table)) + ")", nil
+	default:
+		return "", fmt.Errorf("Invalid identifier %s", typ)
+	}
+}
+
+func (d SQLiteDialect) Supports(f Feature) bool {
+	switch f {
+	case Databases, CreateDatabase, CreateDatabaseIfNotExists, UpdateJoin, ChangeColumn,
+		AlterColumn, TableIndexNames, PrefixIndexes, Enums, ColumnComments, OnUpdateTimestamp:
+		return false
+	case GeneratedColumns, VirtualColumns:
+		return versionAtLeast(d.Version, "3.31")
+	case WindowFunctions:
+		return versionAtLeast(d.Version, "3.25")
+	case RightJoin, FullJoin:
+		return versionAtLeast(d.Version, "3.39")
+	}
+	return true
+}
+
+// sqlite uses a table prefix to simulate databases.
+func sqliteTableName(database, table string) string {
+	if database != "" {
+		return database + "_" + table
+	}
+	return table
+}
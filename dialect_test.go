@@ -0,0 +1,74 @@
+package goql
+
+import (
+	"strconv"
+	"testing"
+)
+
+type testDialect struct {
+	MySQLDialect
+}
+
+func (testDialect) QuoteIdent(s string) string {
+	return "[" + s + "]"
+}
+
+func (testDialect) Placeholder(n int) string {
+	return "@p" + strconv.Itoa(n)
+}
+
+func (testDialect) Func(name string, args []string) (string, bool, error) {
+	if name == "UTC_TIMESTAMP" {
+		return "GETUTCDATE()", true, nil
+	}
+	return "", false, nil
+}
+
+func TestRegisterDialect(t *testing.T) {
+	RegisterDialect("test", testDialect{})
+
+	q, err := ParseQuery("select id, utc_timestamp() from foo where a = ? and b = ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := NewWriter(q, []interface{}{1, 2}, "", "test").Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "SELECT [id], GETUTCDATE() FROM [foo] WHERE [a] = @p1 AND [b] = @p2" {
+		t.Fatal(s)
+	}
+}
+
+func TestWriterDialect(t *testing.T) {
+	q, err := ParseQuery("select true from foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWriter(q, nil, "", "mysql")
+	w.Dialect = SQLiteDialect{}
+	w.EscapeIdents = false
+
+	s, _, err := w.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "SELECT 1 FROM foo" {
+		t.Fatal(s)
+	}
+}
+
+func TestInvalidDriver(t *testing.T) {
+	q, err := ParseQuery("select 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := ToSql(q, nil, "", "oracle"); err == nil {
+		t.Fatal("Expected an invalid driver error")
+	}
+}
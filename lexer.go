@@ -40,6 +40,16 @@ func (l *lexer) run() error {
 		var buf bytes.Buffer
 
 		switch {
+		case (c == 'x' || c == 'X' || c == 'b' || c == 'B') && l.peek() == '\'':
+			if err := l.readBinaryString(c, &buf, token); err != nil {
+				return err
+			}
+
+		case c == '.' && isDecimal(l.peek()) && !l.afterIdent():
+			if err := l.readNumber(c, &buf, token); err != nil {
+				return err
+			}
+
 		case isIdent(c, 0):
 			token.Type = IDENT
 			err := l.readIdent(c, &buf)
@@ -96,20 +106,62 @@ func (l *lexer) run() error {
 				token.Type = MUL
 				token.Str = string(c)
 			case '/':
-				token.Type = DIV
-				token.Str = string(c)
+				if l.peek() == '*' {
+					token.Type = COMMENT
+					err := l.readBlockComment(c, &buf)
+					token.Str = buf.String()
+					if err != nil {
+						return err
+					}
+				} else {
+					token.Type = DIV
+					token.Str = string(c)
+				}
+			case '#':
+				token.Type = COMMENT
+				err := l.readComment(c, &buf)
+				token.Str = buf.String()
+				if err != nil {
+					return err
+				}
 			case '&':
 				token.Type = ANB
 				token.Str = string(c)
+			case '|':
+				if l.peek() == '|' {
+					token.Type = CONCAT
+					token.Str = "||"
+					l.next()
+				} else {
+					token.Type = ORB
+					token.Str = string(c)
+				}
+			case '^':
+				token.Type = XOB
+				token.Str = string(c)
+			case '~':
+				token.Type = NTB
+				token.Str = string(c)
 			case '=':
 				token.Type = EQL
 				token.Str = string(c)
 			case '<':
-				if l.peek() == '=' {
-					token.Type = LEQ
-					token.Str = "<="
+				switch l.peek() {
+				case '=':
 					l.next()
-				} else {
+					if l.peek() == '>' {
+						token.Type = NSEQ
+						token.Str = "<=>"
+						l.next()
+					} else {
+						token.Type = LEQ
+						token.Str = "<="
+					}
+				case '<':
+					token.Type = LSF
+					token.Str = "<<"
+					l.next()
+				default:
 					token.Type = LSS
 					token.Str = string(c)
 				}
@@ -120,7 +172,7 @@ func (l *lexer) run() error {
 					token.Str = ">="
 					l.next()
 				case '>':
-					token.Type = LSF
+					token.Type = RSF
 					token.Str = ">>"
 					l.next()
 				default:
@@ -152,8 +204,28 @@ func (l *lexer) run() error {
 				token.Type = PERIOD
 				token.Str = string(c)
 			case ':':
-				token.Type = COLON
-				token.Str = string(c)
+				// a colon just after an identifier is a namespace: foo:bar
+				if isIdent(l.peek(), 0) && !l.afterIdent() {
+					token.Type = NAMEDPARAM
+					err := l.readIdent(c, &buf)
+					token.Str = buf.String()
+					if err != nil {
+						return err
+					}
+				} else {
+					token.Type = COLON
+					token.Str = string(c)
+				}
+			case '@', '$':
+				if !isIdent(l.peek(), 0) {
+					return l.error(string(c), "Invalid parameter")
+				}
+				token.Type = NAMEDPARAM
+				err := l.readIdent(c, &buf)
+				token.Str = buf.String()
+				if err != nil {
+					return err
+				}
 			case ';':
 				token.Type = SEMICOLON
 				token.Str = string(c)
@@ -168,14 +240,31 @@ func (l *lexer) run() error {
 }
 
 func (l *lexer) readNumber(c byte, buf *bytes.Buffer, token *Token) error {
+	if c == '0' && (l.peek() == 'x' || l.peek() == 'b') {
+		return l.readRadixNumber(buf, token)
+	}
+
 	token.Type = INT
-	err := l.readDecimal(c, buf)
-	token.Str = buf.String()
-	if err != nil {
-		return err
+
+	if c == '.' {
+		// a number that starts with the decimal point: .5
+		buf.WriteByte(c)
+		token.Type = FLOAT
+		err := l.readDecimal(l.next(), buf)
+		token.Str = buf.String()
+		if err != nil {
+			return err
+		}
+	} else {
+		err := l.readDecimal(c, buf)
+		token.Str = buf.String()
+		if err != nil {
+			return err
+		}
 	}
+
 	c = l.peek()
-	if c == '.' {
+	if c == '.' && token.Type == INT {
 		buf.WriteByte(c)
 		c = l.next()
 		c = l.next()
@@ -183,24 +272,126 @@ func (l *lexer) readNumber(c byte, buf *bytes.Buffer, token *Token) error {
 			return l.error(buf.String(), "Invalid number")
 		}
 		token.Type = FLOAT
-		err = l.readDecimal(c, buf)
+		err := l.readDecimal(c, buf)
 		token.Str = buf.String()
 		if err != nil {
 			return err
 		}
 	}
+
+	c = l.peek()
+	if c == 'e' || c == 'E' {
+		buf.WriteByte(l.next())
+		if c = l.peek(); c == '+' || c == '-' {
+			buf.WriteByte(l.next())
+		}
+		c = l.next()
+		if !isDecimal(c) {
+			return l.error(buf.String(), "Invalid number")
+		}
+		token.Type = FLOAT
+		err := l.readDecimal(c, buf)
+		token.Str = buf.String()
+		if err != nil {
+			return err
+		}
+	}
+
+	if isIdent(l.peek(), 1) {
+		return l.error(buf.String(), "Invalid number")
+	}
+
+	return nil
+}
+
+// readRadixNumber reads 0x1F or 0b1010. The token only has the digits.
+func (l *lexer) readRadixNumber(buf *bytes.Buffer, token *Token) error {
+	token.Type = HEX
+	digit := isHex
+	if l.next() == 'b' {
+		token.Type = BIT
+		digit = isBit
+	}
+
+	for isIdent(l.peek(), 1) {
+		c := l.next()
+		if !digit(c) {
+			return l.error(buf.String(), "Invalid number")
+		}
+		buf.WriteByte(c)
+	}
+
+	token.Str = buf.String()
+	if token.Str == "" {
+		return l.error(token.Str, "Invalid number")
+	}
+	return nil
+}
+
+// readBinaryString reads X'ABCD' or b'1010'. The token only has the digits.
+func (l *lexer) readBinaryString(c byte, buf *bytes.Buffer, token *Token) error {
+	token.Type = HEXSTRING
+	digit := isHex
+	if c == 'b' || c == 'B' {
+		token.Type = BIT
+		digit = isBit
+	}
+
+	// the opening quote
+	l.next()
+
+	for {
+		c = l.next()
+		if c == '\'' {
+			break
+		}
+		if !digit(c) {
+			return l.error(buf.String(), "Invalid binary string")
+		}
+		buf.WriteByte(c)
+	}
+
+	token.Str = buf.String()
+
+	// each byte is written with two digits
+	if token.Type == HEXSTRING && len(token.Str)%2 != 0 {
+		return l.error(token.Str, "Invalid binary string")
+	}
 	return nil
 }
 
+// afterIdent returns true if the last token is an identifier that
+// ends just before the current character.
+func (l *lexer) afterIdent() bool {
+	if len(l.Tokens) == 0 {
+		return false
+	}
+
+	t := l.Tokens[len(l.Tokens)-1]
+	return t.Type == IDENT && t.Pos.Line == l.Pos.Line && t.Pos.Column == l.Pos.Column-1
+}
+
 func (l *lexer) addToken(t *Token) {
 	t.Pos = l.Pos
 	t.Pos.Length = len(t.Str)
 	l.Tokens = append(l.Tokens, t)
 }
 
+// readString decodes a string constant. The quote can be escaped
+// writing it twice or with a backslash.
 func (l *lexer) readString(quote byte, b *bytes.Buffer) error {
 	c := l.next()
-	for c != quote {
+	for {
+		if c == quote {
+			if l.peek() != quote {
+				break
+			}
+			b.WriteByte(quote)
+			l.next()
+			c = l.next()
+			continue
+		}
+
 		// Allo multiline strings
 		if c == byte(EOF) {
 			return l.error(b.String(), "unterminated string")
@@ -226,6 +417,14 @@ func (l *lexer) readString(quote byte, b *bytes.Buffer) error {
 				b.WriteByte('\'')
 			case '\\':
 				b.WriteByte('\\')
+			case '0':
+				b.WriteByte(0)
+			case 'Z':
+				b.WriteByte(26)
+			case '%', '_':
+				// they are only escaped in LIKE patterns
+				b.WriteByte('\\')
+				b.WriteByte(c)
 			default:
 				return l.error(b.String(), "Invalid escape sequence")
 			}
@@ -253,6 +452,26 @@ loop:
 	return nil
 }
 
+// readBlockComment reads a /* */ comment. It includes the
+// MySQL /*! versioned */ comments.
+func (l *lexer) readBlockComment(c byte, b *bytes.Buffer) error {
+	b.WriteByte(c)
+	b.WriteByte(l.next())
+	for {
+		c = l.next()
+		if c == byte(EOF) {
+			return l.error(b.String(), "Unclosed comment")
+		}
+
+		b.WriteByte(c)
+
+		if c == '*' && l.peek() == '/' {
+			b.WriteByte(l.next())
+			return nil
+		}
+	}
+}
+
 func (l *lexer) readIdent(c byte, b *bytes.Buffer) error {
 	b.WriteByte(c)
 	for isIdent(l.peek(), 1) {
@@ -331,6 +550,14 @@ func isDecimal(ch byte) bool {
 	return '0' <= ch && ch <= '9'
 }
 
+func isHex(ch byte) bool {
+	return isDecimal(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+func isBit(ch byte) bool {
+	return ch == '0' || ch == '1'
+}
+
 func isIdent(ch byte, pos int) bool {
 	return ch == '_' ||
 		'A' <= ch && ch <= 'Z' ||
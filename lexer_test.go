@@ -17,6 +17,14 @@ func TestLex(t *testing.T) {
 		{"WHERE 1", []Type{WHERE, INT}},
 		{"-- foo bar", []Type{COMMENT}},
 		{"8.99 -- foo foo", []Type{FLOAT, COMMENT}},
+		{"# foo", []Type{COMMENT}},
+		{"1 /* foo\n * bar */ / 2", []Type{INT, COMMENT, DIV, INT}},
+		{"/*!40101 SET NAMES utf8 */;", []Type{COMMENT, SEMICOLON}},
+		{"SELECT 2*3", []Type{SELECT, INT, MUL, INT}},
+		{"a << 2 >> 1 | b || c ^ d & ~e", []Type{IDENT, LSF, INT, RSF, INT, ORB, IDENT, CONCAT, IDENT, XOB, IDENT, ANB, NTB, IDENT}},
+		{"a <=> b <= c", []Type{IDENT, NSEQ, IDENT, LEQ, IDENT}},
+		{"0x1F 0b101 X'ABCD' b'1010'", []Type{HEX, BIT, HEXSTRING, BIT}},
+		{".5 1.5e-3 1E10 t.id", []Type{FLOAT, FLOAT, FLOAT, IDENT, PERIOD, IDENT}},
 		{`SELECT 'asdf
 				  asdfasdf' FROM test`, []Type{SELECT, STRING, FROM, IDENT}},
 	}
@@ -28,6 +36,20 @@ func TestLex(t *testing.T) {
 	}
 }
 
+func TestLexInvalidNumber(t *testing.T) {
+	for _, s := range []string{"0x", "0x1G", "1e", "1.5ex", "12abc", "X'ABC'", "b'102'"} {
+		if err := test(s, nil); err == nil {
+			t.Fatal("Expected error: " + s)
+		}
+	}
+}
+
+func TestLexUnclosedComment(t *testing.T) {
+	if err := test("SELECT 1 /* foo", nil); err == nil {
+		t.Fatal("Expected error")
+	}
+}
+
 func test(s string, types []Type) error {
 	l := newLexer(strings.NewReader(s))
 
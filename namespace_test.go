@@ -226,6 +226,48 @@ func TestNamespace22(t *testing.T) {
 	}
 }
 
+func TestNamespaceDerivedTable(t *testing.T) {
+	query := `SELECT t.id FROM (SELECT id FROM client) AS t LEFT JOIN (SELECT idClient FROM bar:sale) x ON x.idClient = t.id`
+	expected := `SELECT t.id FROM (SELECT id FROM foo_client) AS t LEFT JOIN (SELECT idClient FROM bar_sale) AS x ON x.idClient = t.id`
+	shouldFail := false
+
+	if err := testNamespace(query, expected, "", "foo", false, shouldFail); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNamespaceIndex(t *testing.T) {
+	query := `CREATE INDEX idx_name ON client (name)`
+	expected := `CREATE INDEX foo_idx_name ON foo_client (name)`
+
+	if err := testNamespace(query, expected, "", "foo", false, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// index names are global in sqlite so they can't drop another namespace index.
+	query = `DROP INDEX bar:idx_name`
+
+	if err := testNamespace(query, "", "", "foo", false, true); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNamespaceWriteCTE(t *testing.T) {
+	query := `WITH client AS (SELECT 1 AS id) DELETE FROM client WHERE id IN (SELECT id FROM client)`
+	expected := `WITH client AS (SELECT 1 AS id) DELETE FROM foo_client WHERE id IN (SELECT id FROM client)`
+
+	if err := testNamespace(query, expected, "", "foo", false, false); err != nil {
+		t.Fatal(err)
+	}
+
+	query = `WITH client AS (SELECT 1 AS id) UPDATE client SET name = 'a' WHERE id = 1`
+	expected = `WITH client AS (SELECT 1 AS id) UPDATE foo_client SET name = 'a' WHERE id = 1`
+
+	if err := testNamespace(query, expected, "", "foo", false, false); err != nil {
+		t.Fatal(err)
+	}
+}
+
 // add here tests trying to accept invalid queries, query other database
 // if restricted or any other vulnerability.
 // SQL injection prevention is not possible because they are valid queries.
@@ -242,6 +284,9 @@ func TestInvalidDatabase(t *testing.T) {
 		join items b on a = (select id from db2.x)`,
 		`select 1 from cars WHERE a in (select id from db2.x)`,
 		`select id from cars a UNION select id from db2.x`,
+		`select id from (select id from db2.x) t`,
+		`select id from cars c join (select id from db2.x) t on t.id = c.id`,
+		"drop index idx on db2.cars",
 	}
 
 	for i, s := range queries {
@@ -253,6 +298,18 @@ func TestInvalidDatabase(t *testing.T) {
 			t.Fatalf("%d: Expected invalid database error: %v", i, err)
 		}
 	}
+
+	// mysql executes the text of /*! */ comments.
+	q, err := ParseQuery("select * from cars /*! UNION SELECT * FROM db2.secret */")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWriter(q, nil, "db1", "mysql")
+	w.Comments = true
+	if _, _, err := w.Write(); err == nil {
+		t.Fatal("Expected invalid comment error")
+	}
 }
 
 // Make sure to don't accept invalid queries
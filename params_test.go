@@ -1,6 +1,10 @@
 package goql
 
-import "testing"
+import (
+	"math"
+	"strings"
+	"testing"
+)
 
 func TestParseSelectParams(t *testing.T) {
 	p := NewStrParser("select * from foo where name like 'bar'")
@@ -113,3 +117,127 @@ func TestParseSelectParams4(t *testing.T) {
 		t.Fatal(sel.Params)
 	}
 }
+
+func TestNamedParams(t *testing.T) {
+	q, err := ParseQuery("select * from foo where a = :a and (b = @b or c = :a) limit $n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := map[string]interface{}{"a": 1, "b": "x", "n": 10}
+
+	s, ps, err := ToSql(q, []interface{}{params}, "", "postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT * FROM "foo" WHERE "a" = $1 AND ("b" = $2 OR "c" = $3) LIMIT $4` {
+		t.Fatal(s)
+	}
+
+	if len(ps) != 4 || ps[0] != 1 || ps[1] != "x" || ps[2] != 1 || ps[3] != 10 {
+		t.Fatal(ps)
+	}
+}
+
+func TestNamedParamsStruct(t *testing.T) {
+	q, err := ParseQuery("select * from bar:foo where id in (:ids) and name = :name and deleted = :deleted")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := struct {
+		IDs     []interface{} `db:"ids"`
+		Name    string
+		Deleted interface{}
+	}{[]interface{}{1, 2}, "x", nil}
+
+	s, ps, err := toSQL(false, q, []interface{}{&params}, "", "mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT * FROM bar_foo WHERE id IN (1, 2) AND name = ? AND deleted IS NULL` {
+		t.Fatal(s)
+	}
+
+	if len(ps) != 1 || ps[0] != "x" {
+		t.Fatal(ps)
+	}
+}
+
+func TestNamedParamsErrors(t *testing.T) {
+	q, err := ParseQuery("select * from foo where a = :a and b = ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := toSQL(false, q, []interface{}{map[string]interface{}{"a": 1}}, "", "mysql"); err == nil {
+		t.Fatal("Expected an error mixing named and positional parameters")
+	}
+
+	q, err = ParseQuery("select * from foo where a = :a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := toSQL(false, q, []interface{}{map[string]interface{}{"b": 1}}, "", "mysql"); err == nil {
+		t.Fatal("Expected an error with a missing parameter")
+	}
+
+	if _, _, err := toSQL(false, q, []interface{}{1}, "", "mysql"); err == nil {
+		t.Fatal("Expected an error without named values")
+	}
+}
+
+func TestNumericParams(t *testing.T) {
+	p := NewStrParser("select * from foo where a = 0x1F and b = b'101' and c = X'4142' and d = -1.5e-3 and e = .5 and f = -2")
+	p.ReplaceParams = true
+
+	q, err := p.ParseQuery()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := q.(*SelectQuery).Params
+	if len(params) != 6 ||
+		params[0] != int64(31) ||
+		params[1] != int64(5) ||
+		string(params[2].([]byte)) != "AB" ||
+		params[3] != -0.0015 ||
+		params[4] != 0.5 ||
+		params[5] != -2 {
+		t.Fatal(params)
+	}
+
+	p = NewStrParser("select * from foo where a = 0xFFFFFFFFFFFFFFFF and b = b'" + strings.Repeat("1", 64) + "' and c = b'' and d = x''")
+	p.ReplaceParams = true
+
+	q, err = p.ParseQuery()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params = q.(*SelectQuery).Params
+	if len(params) != 4 ||
+		params[0] != uint64(math.MaxUint64) ||
+		params[1] != uint64(math.MaxUint64) ||
+		len(params[2].([]byte)) != 0 ||
+		len(params[3].([]byte)) != 0 {
+		t.Fatal(params)
+	}
+}
+
+func TestParseCreateParams(t *testing.T) {
+	p := NewStrParser("create table a (b int default 3 check (b > 0))")
+	p.ReplaceParams = true
+
+	if _, err := p.ParseQuery(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the databases don't accept parameters in a definition.
+	if len(p.Params) > 0 {
+		t.Fatal(p.Params)
+	}
+}
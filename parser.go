@@ -1,8 +1,10 @@
 package goql
 
 import (
+	"encoding/hex"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
 	"strings"
 )
@@ -11,7 +13,8 @@ import (
 func Select(code string, params ...interface{}) (*SelectQuery, error) {
 	// auto add the select part if it is ommited
 	if len(code) > 7 {
-		if !strings.HasPrefix(strings.ToUpper(code[:7]), "SELECT ") {
+		start := strings.ToUpper(code[:7])
+		if !strings.HasPrefix(start, "SELECT ") && !strings.HasPrefix(start, "WITH ") {
 			code = "SELECT " + code
 		}
 	}
@@ -68,6 +71,9 @@ type Parser struct {
 
 	lexer   *lexer
 	lexIdex int
+
+	// if it is parsing the assignments of an upsert
+	upsert bool
 }
 
 func NewStrParser(code string) *Parser {
@@ -110,12 +116,10 @@ loop:
 		// reset params for each query
 		p.Params = nil
 
+		leading := p.readComments(-1)
+
 		t := p.peek()
 		switch t.Type {
-		case COMMENT:
-			p.next()
-			continue
-
 		case SELECT:
 			n, err := p.parseSelect()
 			if err != nil {
@@ -148,6 +152,16 @@ loop:
 			n.Params = p.Params
 			queries = append(queries, n)
 
+		case IDENT:
+			if !isWord(t, "WITH") {
+				return nil, fmt.Errorf("SQL Parser: %v", newError(t, "Unexpected '%s' (%v)", t.Str, t.Type))
+			}
+			n, err := p.parseWithQuery()
+			if err != nil {
+				return nil, fmt.Errorf("SQL Parser: %v", err)
+			}
+			queries = append(queries, n)
+
 		case CREATE:
 			n, err := p.parseCreate()
 			if err != nil {
@@ -177,30 +191,143 @@ loop:
 			queries = append(queries, n)
 
 		case EOF:
+			// the comments at the end of the script belong to the last query
+			if len(queries) > 0 {
+				c := queries[len(queries)-1].QueryComments()
+				c.Trailing = append(c.Trailing, leading...)
+			}
 			break loop
 		default:
 			return nil, fmt.Errorf("SQL Parser: %v", newError(t, "Unexpected '%s' (%v)", t.Str, t.Type))
 		}
 
-	inner:
-		for {
-			t = p.peek()
-			switch t.Type {
-			case COMMENT:
-				p.next()
-			case SEMICOLON:
+		c := queries[len(queries)-1].QueryComments()
+		c.Leading = leading
+		c.Trailing = p.readComments(-1)
+
+		t = p.peek()
+		switch t.Type {
+		case SEMICOLON:
+			p.next()
+			// the comments in the same line of the semicolon are of this query
+			c.Trailing = append(c.Trailing, p.readComments(t.Pos.Line)...)
+		case EOF:
+			break loop
+		default:
+			return nil, fmt.Errorf("SQL Parser: %v", newError(t, "Unexpected '%s' (%v)", t.Str, t.Type))
+		}
+	}
+
+	return queries, nil
+}
+
+// parseWithQuery parses a query that starts with common table expressions.
+func (p *Parser) parseWithQuery() (Query, error) {
+	with, err := p.parseWith()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	switch t.Type {
+	case SELECT:
+		n, err := p.parseSelect()
+		if err != nil {
+			return nil, err
+		}
+		n.With = with
+		n.Params = p.Params
+		return n, nil
+
+	case UPDATE:
+		n, err := p.parseUpdate()
+		if err != nil {
+			return nil, err
+		}
+		n.With = with
+		n.Params = p.Params
+		return n, nil
+
+	case DELETE:
+		n, err := p.parseDelete()
+		if err != nil {
+			return nil, err
+		}
+		n.With = with
+		n.Params = p.Params
+		return n, nil
+
+	default:
+		return nil, newError(t, "Unexpected '%s' after WITH", t.Str)
+	}
+}
+
+func (p *Parser) parseWith() (*WithClause, error) {
+	t, err := p.acceptString("WITH")
+	if err != nil {
+		return nil, err
+	}
+
+	w := &WithClause{Pos: t.Pos}
+
+	if strings.EqualFold(p.peek().Str, "RECURSIVE") {
+		p.next()
+		w.Recursive = true
+	}
+
+	for {
+		t, err := p.accept(IDENT)
+		if err != nil {
+			return nil, err
+		}
+
+		cte := &CommonTableExpr{Pos: t.Pos, Name: t.Str}
+
+		if p.peek().Type == LPAREN {
+			p.next()
+			for {
+				name, err := p.parseColumnName()
+				if err != nil {
+					return nil, err
+				}
+				cte.Columns = append(cte.Columns, name)
+
+				if p.peek().Type != COMMA {
+					break
+				}
 				p.next()
-				break inner
-			case EOF:
-				break loop
-			default:
-				return nil, fmt.Errorf("SQL Parser: %v", newError(t, "Unexpected '%s' (%v)", t.Str, t.Type))
 			}
+			if _, err := p.accept(RPAREN); err != nil {
+				return nil, err
+			}
+		}
+
+		if _, err := p.accept(AS); err != nil {
+			return nil, err
+		}
+
+		if _, err := p.accept(LPAREN); err != nil {
+			return nil, err
+		}
+
+		cte.Query, err = p.parseSelect()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := p.accept(RPAREN); err != nil {
+			return nil, err
 		}
 
+		w.Tables = append(w.Tables, cte)
+
+		if p.peek().Type != COMMA {
+			break
+		}
+		p.next()
 	}
 
-	return queries, nil
+	return w, nil
 }
 
 func (p *Parser) parseDelete() (*DeleteQuery, error) {
@@ -381,22 +508,151 @@ func (p *Parser) parseInsert() (*InsertQuery, error) {
 		return nil, err
 	}
 
-	_, err = p.accept(LPAREN)
+	for {
+		t, err := p.accept(LPAREN)
+		if err != nil {
+			return nil, err
+		}
+
+		values, err := p.parseExpressionList()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(insert.Columns) > 0 && len(values) != len(insert.Columns) {
+			return nil, newError(t, "Expected %d values, got %d", len(insert.Columns), len(values))
+		}
+
+		if len(insert.Rows) > 0 && len(values) != len(insert.Rows[0]) {
+			return nil, newError(t, "Expected %d values, got %d", len(insert.Rows[0]), len(values))
+		}
+
+		insert.Rows = append(insert.Rows, values)
+
+		_, err = p.accept(RPAREN)
+		if err != nil {
+			return nil, err
+		}
+
+		if p.peek().Type != COMMA {
+			break
+		}
+		p.next()
+	}
+
+	if p.peek().Type == ON {
+		c, err := p.parseOnConflict()
+		if err != nil {
+			return nil, err
+		}
+		insert.OnConflict = c
+	}
+
+	return insert, nil
+}
+
+// parses ON DUPLICATE KEY UPDATE or ON CONFLICT [(cols)] DO NOTHING | DO UPDATE SET
+func (p *Parser) parseOnConflict() (*OnConflict, error) {
+	t, err := p.accept(ON)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &OnConflict{Pos: t.Pos}
+
+	switch strings.ToUpper(p.peek().Str) {
+	case "DUPLICATE":
+		p.next()
+		if _, err := p.acceptString("KEY"); err != nil {
+			return nil, err
+		}
+		if _, err := p.accept(UPDATE); err != nil {
+			return nil, err
+		}
+
+	case "CONFLICT":
+		p.next()
+		if p.peek().Type == LPAREN {
+			p.next()
+			for {
+				col, err := p.accept(IDENT)
+				if err != nil {
+					return nil, err
+				}
+				c.Columns = append(c.Columns, col.Str)
+
+				if p.peek().Type != COMMA {
+					break
+				}
+				p.next()
+			}
+			if _, err := p.accept(RPAREN); err != nil {
+				return nil, err
+			}
+		}
+
+		if _, err := p.acceptString("DO"); err != nil {
+			return nil, err
+		}
+
+		t := p.next()
+		switch {
+		case strings.EqualFold(t.Str, "NOTHING"):
+			c.DoNothing = true
+			return c, nil
+		case t.Type == UPDATE:
+			if _, err := p.accept(SET); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, newError(t, "Expecting NOTHING or UPDATE, got %s", t.Str)
+		}
+
+	default:
+		t := p.peek()
+		return nil, newError(t, "Expecting DUPLICATE or CONFLICT, got %s", t.Str)
+	}
+
+	p.upsert = true
+	update, err := p.parseColumnValues()
+	p.upsert = false
 	if err != nil {
 		return nil, err
 	}
 
-	values, err := p.parseExpressionList()
+	if len(update) == 0 {
+		return nil, newError(p.peek(), "Expecting the columns to update")
+	}
+
+	c.Update = update
+	return c, nil
+}
+
+// parses VALUES(col) in an upsert
+func (p *Parser) parseExcludedExpr() (*ExcludedExpr, error) {
+	t, err := p.accept(VALUES)
 	if err != nil {
 		return nil, err
 	}
-	insert.Values = values
 
-	_, err = p.accept(RPAREN)
+	if !p.upsert {
+		return nil, newError(t, "Unexpected VALUES")
+	}
+
+	if _, err := p.accept(LPAREN); err != nil {
+		return nil, err
+	}
+
+	name, err := p.accept(IDENT)
 	if err != nil {
 		return nil, err
 	}
-	return insert, nil
+
+	if _, err := p.accept(RPAREN); err != nil {
+		return nil, err
+	}
+
+	return &ExcludedExpr{Pos: t.Pos, Name: name.Str}, nil
 }
 
 func (p *Parser) parseColumnValues() ([]ColumnValue, error) {
@@ -680,11 +936,14 @@ func (p *Parser) parseDrop() (Query, error) {
 		return nil, err
 	}
 
-	switch p.next().Type {
-	case DATABASE:
+	n := p.next()
+	switch {
+	case n.Type == DATABASE:
 		return p.parseDropDatabase(t.Pos)
-	case TABLE:
+	case n.Type == TABLE:
 		return p.parseDropTable(t.Pos)
+	case n.Type == IDENT && strings.EqualFold(n.Str, "INDEX"):
+		return p.parseDropIndex(t.Pos)
 	default:
 		return nil, newError(t, "Unexpected %s", t.Type)
 	}
@@ -731,6 +990,44 @@ func (p *Parser) parseDropTable(pos Position) (*DropTableQuery, error) {
 	return q, nil
 }
 
+// parses DROP INDEX [IF EXISTS] [db.]name [ON [db.]table]
+func (p *Parser) parseDropIndex(pos Position) (*DropIndexQuery, error) {
+	q := &DropIndexQuery{Pos: pos}
+
+	if strings.ToUpper(p.peek().Str) == "IF" {
+		p.next()
+		if _, err := p.accept(EXISTS); err != nil {
+			return nil, err
+		}
+		q.IfExists = true
+	}
+
+	db, name, err := p.parseSelectorIdent()
+	if err != nil {
+		return nil, err
+	}
+	q.Database = db
+	q.Name = name
+
+	if p.peek().Type == ON {
+		p.next()
+		t := p.peek()
+		db, table, err := p.parseSelectorIdent()
+		if err != nil {
+			return nil, err
+		}
+		if db != "" {
+			if q.Database != "" && q.Database != db {
+				return nil, newError(t, "Invalid database %s", db)
+			}
+			q.Database = db
+		}
+		q.Table = table
+	}
+
+	return q, nil
+}
+
 func (p *Parser) parseAlterDrop(pos Position, db, table string) (*AlterDropQuery, error) {
 	q := &AlterDropQuery{Pos: pos}
 	q.Database = db
@@ -813,6 +1110,12 @@ func (p *Parser) parseCreate() (Query, error) {
 		return p.parseCreateDatabase(t)
 	case TABLE:
 		return p.parseCreateTable(t)
+	case IDENT:
+		switch strings.ToUpper(q.Str) {
+		case "UNIQUE", "INDEX":
+			return p.parseCreateIndex(t)
+		}
+		return nil, newError(t, "Unexpected %s", q.Str)
 	default:
 		return nil, newError(t, "Unexpected %s", q.Type)
 	}
@@ -845,12 +1148,18 @@ func (p *Parser) parseCreateDatabase(t *Token) (*CreateDatabaseQuery, error) {
 	return s, nil
 }
 
-func (p *Parser) parseCreateTable(t *Token) (*CreateTableQuery, error) {
-	if _, err := p.accept(TABLE); err != nil {
-		return nil, err
+// parses CREATE [UNIQUE] INDEX [IF NOT EXISTS] name ON [db.]table (col[(length)], ...)
+func (p *Parser) parseCreateIndex(t *Token) (*CreateIndexQuery, error) {
+	s := &CreateIndexQuery{Pos: t.Pos}
+
+	if strings.ToUpper(p.peek().Str) == "UNIQUE" {
+		p.next()
+		s.Unique = true
 	}
 
-	s := &CreateTableQuery{Pos: t.Pos}
+	if _, err := p.acceptString("INDEX"); err != nil {
+		return nil, err
+	}
 
 	if strings.ToUpper(p.peek().Str) == "IF" {
 		p.next()
@@ -863,54 +1172,124 @@ func (p *Parser) parseCreateTable(t *Token) (*CreateTableQuery, error) {
 		s.IfNotExists = true
 	}
 
-	table, err := p.parsePrefixedIdent()
+	name, err := p.parsePrefixedIdent()
 	if err != nil {
 		return nil, err
 	}
-	s.Name = table
+	s.Name = name
 
-	if _, err := p.accept(LPAREN); err != nil {
+	if _, err := p.accept(ON); err != nil {
 		return nil, err
 	}
 
-	cols, err := p.parseCreateColumns()
+	db, table, err := p.parseSelectorIdent()
 	if err != nil {
 		return nil, err
 	}
-	s.Columns = cols
+	s.Database = db
+	s.Table = table
 
-	t = p.peek()
-	if strings.EqualFold(t.Str, "PRIMARY") {
-		p.next()
-		if _, err := p.acceptString("KEY"); err != nil {
-			return nil, err
-		}
-		if _, err := p.accept(LPAREN); err != nil {
-			return nil, err
-		}
-		if _, err := p.acceptString("id"); err != nil {
-			return nil, err
-		}
-		if _, err := p.accept(RPAREN); err != nil {
-			return nil, err
-		}
-		if _, err := p.accept(COMMA); err != nil {
-			return nil, err
-		}
+	if _, err := p.accept(LPAREN); err != nil {
+		return nil, err
 	}
 
-loop:
 	for {
-		t = p.peek()
-		switch t.Type {
-		case IDENT:
+		name, err := p.parseColumnName()
+		if err != nil {
+			return nil, err
+		}
+
+		c := &IndexColumn{Name: name}
+
+		if p.peek().Type == LPAREN {
+			p.next()
+			l, err := p.accept(INT)
+			if err != nil {
+				return nil, err
+			}
+			c.Length = l.Str
+			if _, err := p.accept(RPAREN); err != nil {
+				return nil, err
+			}
+		}
+
+		s.Columns = append(s.Columns, c)
+
+		if p.peek().Type != COMMA {
+			break
+		}
+
+		p.next()
+	}
+
+	if _, err := p.accept(RPAREN); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (p *Parser) parseCreateTable(t *Token) (*CreateTableQuery, error) {
+	if _, err := p.accept(TABLE); err != nil {
+		return nil, err
+	}
+
+	s := &CreateTableQuery{Pos: t.Pos}
+
+	if strings.ToUpper(p.peek().Str) == "IF" {
+		p.next()
+		if _, err := p.accept(NOT); err != nil {
+			return nil, err
+		}
+		if _, err := p.accept(EXISTS); err != nil {
+			return nil, err
+		}
+		s.IfNotExists = true
+	}
+
+	table, err := p.parsePrefixedIdent()
+	if err != nil {
+		return nil, err
+	}
+	s.Name = table
+
+	if _, err := p.accept(LPAREN); err != nil {
+		return nil, err
+	}
+
+	cols, err := p.parseCreateColumns()
+	if err != nil {
+		return nil, err
+	}
+	s.Columns = cols
+
+loop:
+	for {
+		t = p.peek()
+		switch t.Type {
+		case IDENT:
 			switch strings.ToUpper(t.Str) {
+			case "PRIMARY":
+				if s.PrimaryKey != nil {
+					return nil, newError(t, "Multiple primary keys")
+				}
+				columns, err := p.parsePrimaryKey()
+				if err != nil {
+					return nil, err
+				}
+				s.PrimaryKey = columns
 			case "UNIQUE":
 				c, err := p.parseUniqueKey()
 				if err != nil {
 					return nil, err
 				}
 				s.Constraints = append(s.Constraints, c)
+			case "CHECK":
+				e, err := p.parseCheck()
+				if err != nil {
+					return nil, err
+				}
+				s.Constraints = append(s.Constraints, &Check{Expr: e})
 			default:
 				return nil, newError(t, "Unexpected %s", t.Str)
 			}
@@ -950,16 +1329,24 @@ func (p *Parser) parseConstraint() (CreateTableConstraint, error) {
 
 	name := t.Str
 
-	t, err = p.accept(IDENT)
-	if err != nil {
-		return nil, err
+	t = p.peek()
+	if t.Type != IDENT {
+		return nil, newError(t, "Unexpected %s", t.Str)
 	}
 
 	switch strings.ToUpper(t.Str) {
 	case "FOREIGN":
+		p.next()
 		return p.parseFKConstraint(name)
 	case "UNIQUE":
+		p.next()
 		return p.parseUniqueConstraint(name)
+	case "CHECK":
+		e, err := p.parseCheck()
+		if err != nil {
+			return nil, err
+		}
+		return &Check{Name: name, Expr: e}, nil
 	default:
 		return nil, newError(t, "Unexpected %s", t.Str)
 	}
@@ -1056,6 +1443,40 @@ loop:
 	return c, nil
 }
 
+func (p *Parser) parsePrimaryKey() ([]string, error) {
+	if _, err := p.acceptString("PRIMARY"); err != nil {
+		return nil, err
+	}
+	if _, err := p.acceptString("KEY"); err != nil {
+		return nil, err
+	}
+	if _, err := p.accept(LPAREN); err != nil {
+		return nil, err
+	}
+
+	var columns []string
+
+	for {
+		name, err := p.parseColumnName()
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, name)
+
+		if p.peek().Type != COMMA {
+			break
+		}
+
+		p.next()
+	}
+
+	if _, err := p.accept(RPAREN); err != nil {
+		return nil, err
+	}
+
+	return columns, nil
+}
+
 func (p *Parser) parseUniqueKey() (*Constraint, error) {
 	if _, err := p.acceptString("UNIQUE"); err != nil {
 		return nil, err
@@ -1107,8 +1528,8 @@ loop:
 		case CONSTRAINT:
 			break loop
 		case IDENT:
-			switch t.Str {
-			case "PRIMARY", "UNIQUE":
+			switch strings.ToUpper(t.Str) {
+			case "PRIMARY", "UNIQUE", "CHECK":
 				break loop
 			}
 		}
@@ -1166,43 +1587,158 @@ func (p *Parser) parseCreateColumn() (*CreateColumn, error) {
 		return nil, err
 	}
 
-	nullable := true
-	if p.peek().Type == NOT {
-		p.next()
-		nullable = false
-	}
-	if p.peek().Type == NULL {
-		p.next()
-		c.Nullable = nullable
-	}
-
-	t := p.peek()
-	if t.Str == "AUTO_INCREMENT" {
-		if !strings.EqualFold(name, "id") {
-			return nil, newError(t, "AUTO_INCREMENT is only supported on ID columns: %s", name)
-		}
+	if strings.EqualFold(p.peek().Str, "UNSIGNED") {
 		p.next()
+		c.Unsigned = true
 	}
 
-	t = p.peek()
-	if t.Type == DEFAULT {
-		p.next()
-		t = p.next()
+loop:
+	for {
+		t := p.peek()
 		switch t.Type {
-		case STRING:
-			c.Default = "'" + t.Str + "'"
+		case NOT:
+			p.next()
+			if _, err := p.accept(NULL); err != nil {
+				return nil, err
+			}
+			c.Nullable = false
+
+		case NULL:
+			p.next()
+			c.Nullable = true
+
+		case DEFAULT:
+			p.next()
+			e, err := p.parseDDLExpr(p.parseSignedFactor)
+			if err != nil {
+				return nil, err
+			}
+			c.Default = e
+
+		case ON:
+			p.next()
+			if _, err := p.accept(UPDATE); err != nil {
+				return nil, err
+			}
+			e, err := p.parseDDLExpr(p.parseSignedFactor)
+			if err != nil {
+				return nil, err
+			}
+			c.OnUpdate = e
+
+		case IDENT:
+			switch strings.ToUpper(t.Str) {
+			case "AUTO_INCREMENT", "AUTOINCREMENT":
+				p.next()
+				c.AutoIncrement = true
+			case "PRIMARY":
+				p.next()
+				if _, err := p.acceptString("KEY"); err != nil {
+					return nil, err
+				}
+				c.PrimaryKey = true
+			case "CHECK":
+				e, err := p.parseCheck()
+				if err != nil {
+					return nil, err
+				}
+				c.Check = e
+			case "COMMENT":
+				p.next()
+				s, err := p.accept(STRING)
+				if err != nil {
+					return nil, err
+				}
+				c.Comment = s.Str
+			case "GENERATED":
+				p.next()
+				if _, err := p.acceptString("ALWAYS"); err != nil {
+					return nil, err
+				}
+				if err := p.parseGenerated(c); err != nil {
+					return nil, err
+				}
+			default:
+				break loop
+			}
 
-		case INT, FLOAT, TRUE, FALSE:
-			c.Default = t.Str
+		case AS:
+			if err := p.parseGenerated(c); err != nil {
+				return nil, err
+			}
 
 		default:
-			return nil, newError(t, "Invalid default value type %s", t.Str)
+			break loop
 		}
 	}
 
 	return c, nil
 }
 
+// parses CHECK (expr)
+func (p *Parser) parseCheck() (Expr, error) {
+	if _, err := p.acceptString("CHECK"); err != nil {
+		return nil, err
+	}
+
+	if _, err := p.accept(LPAREN); err != nil {
+		return nil, err
+	}
+
+	e, err := p.parseDDLExpr(p.parseBooleanExpr)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.accept(RPAREN); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// parseDDLExpr parses an expression of a column definition. Constants are
+// not replaced with parameters because the databases don't accept them
+// in a CREATE or ALTER.
+func (p *Parser) parseDDLExpr(parse func() (Expr, error)) (Expr, error) {
+	replace := p.ReplaceParams
+	p.ReplaceParams = false
+	defer func() { p.ReplaceParams = replace }()
+
+	return parse()
+}
+
+// parses AS (expr) [STORED | VIRTUAL] of a generated column.
+func (p *Parser) parseGenerated(c *CreateColumn) error {
+	if _, err := p.accept(AS); err != nil {
+		return err
+	}
+
+	if _, err := p.accept(LPAREN); err != nil {
+		return err
+	}
+
+	e, err := p.parseDDLExpr(p.parseBooleanExpr)
+	if err != nil {
+		return err
+	}
+	c.Generated = e
+
+	if _, err := p.accept(RPAREN); err != nil {
+		return err
+	}
+
+	switch strings.ToUpper(p.peek().Str) {
+	case "STORED":
+		p.next()
+		c.Stored = true
+	case "VIRTUAL":
+		p.next()
+	}
+
+	return nil
+}
+
 func (p *Parser) parseColumnSize(c *CreateColumn) error {
 	if p.peek().Type != LPAREN {
 		return nil
@@ -1255,6 +1791,42 @@ func (p *Parser) parseColumnType(c *CreateColumn) error {
 		c.Type = Blob
 	case DATETIME:
 		c.Type = DatTime
+	case IDENT:
+		// not reserved words because they are also common column names.
+		switch strings.ToUpper(t.Str) {
+		case "INTEGER":
+			c.Type = Int
+		case "BIGINT":
+			c.Type = BigInt
+		case "SMALLINT":
+			c.Type = SmallInt
+		case "FLOAT":
+			c.Type = Float
+		case "DOUBLE":
+			c.Type = Double
+			if strings.EqualFold(p.peek().Str, "PRECISION") {
+				p.next()
+			}
+		case "DATE":
+			c.Type = Date
+		case "TIME":
+			c.Type = Time
+		case "TIMESTAMP":
+			c.Type = Timestamp
+		case "JSON":
+			c.Type = JSON
+		case "UUID":
+			c.Type = UUID
+		case "LONGTEXT":
+			c.Type = LongText
+		case "LONGBLOB":
+			c.Type = LongBlob
+		case "ENUM":
+			c.Type = Enum
+			return p.parseEnumValues(c)
+		default:
+			return newError(t, "Invalid column type %s", t.Str)
+		}
 	default:
 		return newError(t, "Invalid column type %s", t.Str)
 	}
@@ -1262,35 +1834,94 @@ func (p *Parser) parseColumnType(c *CreateColumn) error {
 	return nil
 }
 
-func (p *Parser) parseSelect() (*SelectQuery, error) {
-	t, err := p.accept(SELECT)
-	if err != nil {
-		return nil, err
+// parses the values of ENUM('a', 'b', ...)
+func (p *Parser) parseEnumValues(c *CreateColumn) error {
+	if _, err := p.accept(LPAREN); err != nil {
+		return err
 	}
 
-	s := &SelectQuery{
-		Pos: t.Pos,
-	}
+	for {
+		t, err := p.accept(STRING)
+		if err != nil {
+			return err
+		}
+		c.Values = append(c.Values, t.Str)
+
+		if p.peek().Type != COMMA {
+			break
+		}
 
-	if p.peek().Type == DISTINCT {
-		s.Distinct = true
 		p.next()
 	}
 
-	columns, err := p.parseSelectColumns()
+	_, err := p.accept(RPAREN)
+	return err
+}
+
+func (p *Parser) parseSelect() (*SelectQuery, error) {
+	s, err := p.parseSelectCore()
 	if err != nil {
 		return nil, err
 	}
-	s.Columns = columns
 
-	// a simple select without from part
-	switch p.peek().Type {
-	case EOF, RPAREN:
-		return s, nil
+	union, err := p.parseUnion()
+	if err != nil {
+		return nil, err
 	}
+	s.UnionPart = union
 
-	if p.peek().Type == FROM {
-		from, err := p.parseFrom()
+	order, err := p.parseOrderBy()
+	if err != nil {
+		return nil, err
+	}
+	s.OrderByPart = order
+
+	limit, err := p.parseLimit()
+	if err != nil {
+		return nil, err
+	}
+	s.LimitPart = limit
+
+	ok, err := p.parseForUpdate()
+	if err != nil {
+		return nil, err
+	}
+	s.ForUpdate = ok
+
+	return s, nil
+}
+
+// parseSelectCore parses a select until the ORDER BY. It is also
+// each select of a compound.
+func (p *Parser) parseSelectCore() (*SelectQuery, error) {
+	t, err := p.accept(SELECT)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SelectQuery{
+		Pos: t.Pos,
+	}
+
+	if p.peek().Type == DISTINCT {
+		s.Distinct = true
+		p.next()
+	}
+
+	columns, err := p.parseSelectColumns()
+	if err != nil {
+		return nil, err
+	}
+	s.Columns = columns
+
+	// a simple select without from part
+	switch p.peek().Type {
+	case EOF, RPAREN:
+		return s, nil
+	}
+
+	if p.peek().Type == FROM {
+		from, err := p.parseFrom()
 		if err != nil {
 			return nil, err
 		}
@@ -1315,62 +1946,79 @@ func (p *Parser) parseSelect() (*SelectQuery, error) {
 		if ok {
 			s.HavingPart = having
 		}
-	}
 
-	order, err := p.parseOrderBy()
-	if err != nil {
-		return nil, err
+		windows, err := p.parseWindows()
+		if err != nil {
+			return nil, err
+		}
+		s.Windows = windows
 	}
-	s.OrderByPart = order
 
-	limit, err := p.parseLimit()
-	if err != nil {
-		return nil, err
-	}
-	s.LimitPart = limit
+	return s, nil
+}
 
-	union, err := p.parseUnion()
-	if err != nil {
-		return nil, err
+// setOperation returns the set operation that starts in the next token or
+// NOTSET. INTERSECT and EXCEPT are not reserved words so they are only an
+// operation if a query follows them.
+func (p *Parser) setOperation() Type {
+	t := p.peek()
+	if t.Type == UNION {
+		return UNION
 	}
-	s.UnionPart = union
 
-	ok, err := p.parseForUpdate()
-	if err != nil {
-		return nil, err
+	var tp Type
+	switch {
+	case isWord(t, "INTERSECT"):
+		tp = INTERSECT
+	case isWord(t, "EXCEPT"):
+		tp = EXCEPT
+	default:
+		return NOTSET
 	}
-	s.ForUpdate = ok
-
-	return s, nil
-}
 
-func (p *Parser) parseUnion() ([]*SelectQuery, error) {
-	if p.peek().Type != UNION {
-		return nil, nil
+	switch k := p.peekTwo(); k.Type {
+	case SELECT, DISTINCT:
+		return tp
+	case IDENT:
+		if strings.EqualFold(k.Str, "ALL") {
+			return tp
+		}
 	}
+	return NOTSET
+}
 
-	p.next()
-
-	var queries []*SelectQuery
+// parseUnion parses the set operations: UNION [ALL], INTERSECT [ALL]
+// and EXCEPT [ALL].
+func (p *Parser) parseUnion() ([]*SetOperation, error) {
+	var ops []*SetOperation
 
-loop:
 	for {
-		q, err := p.parseSelect()
-		if err != nil {
-			return nil, err
+		tp := p.setOperation()
+		if tp == NOTSET {
+			return ops, nil
 		}
-		queries = append(queries, q)
+
+		t := p.next()
+		op := &SetOperation{Pos: t.Pos, Type: tp}
 
 		switch p.peek().Type {
-		case UNION:
+		case IDENT:
+			if strings.EqualFold(p.peek().Str, "ALL") {
+				p.next()
+				op.All = true
+			}
+		case DISTINCT:
 			p.next()
-			continue
-		default:
-			break loop
 		}
-	}
 
-	return queries, nil
+		q, err := p.parseSelectCore()
+		if err != nil {
+			return nil, err
+		}
+		op.Query = q
+
+		ops = append(ops, op)
+	}
 }
 
 func (p *Parser) parseForUpdate() (bool, error) {
@@ -1396,21 +2044,21 @@ func (p *Parser) parseLimit() (*Limit, error) {
 
 	rows := p.peek()
 	switch rows.Type {
-	case INT, QUESTION:
+	case INT, QUESTION, NAMEDPARAM:
 		p.next()
 	default:
 		return nil, newError(t, "Unexpected %s after LIMIT", t.Str)
 	}
 
-	e := &ConstantExpr{rows.Pos, INT, rows.Str}
+	e := limitValue(rows)
 	limit := &Limit{Pos: t.Pos, RowCount: e}
 
 	if p.peek().Type == COMMA {
 		p.next()
 
 		offset := p.peek()
-		switch rows.Type {
-		case INT, QUESTION:
+		switch offset.Type {
+		case INT, QUESTION, NAMEDPARAM:
 			p.next()
 		default:
 			return nil, newError(t, "Unexpected %s after LIMIT", t.Str)
@@ -1419,12 +2067,24 @@ func (p *Parser) parseLimit() (*Limit, error) {
 		// when both values are specified then the first one is the offset
 		// and the second is the number of rows.
 		limit.Offset = e
-		limit.RowCount = &ConstantExpr{offset.Pos, INT, offset.Str}
+		limit.RowCount = limitValue(offset)
 	}
 
 	return limit, nil
 }
 
+// limitValue returns the expression for a LIMIT value that can
+// be a number or a parameter.
+func limitValue(t *Token) Expr {
+	switch t.Type {
+	case QUESTION:
+		return &ParameterExpr{t.Pos, ""}
+	case NAMEDPARAM:
+		return &ParameterExpr{t.Pos, t.Str[1:]}
+	}
+	return &ConstantExpr{t.Pos, INT, t.Str}
+}
+
 func (p *Parser) parseOrderBy() ([]*OrderColumn, error) {
 	if p.peek().Type != ORDER {
 		return nil, nil
@@ -1440,7 +2100,7 @@ func (p *Parser) parseOrderBy() ([]*OrderColumn, error) {
 
 loop:
 	for {
-		col, err := p.parseColumnNameExpr()
+		col, err := p.parseBooleanExpr()
 		if err != nil {
 			return nil, err
 		}
@@ -1481,6 +2141,41 @@ func (p *Parser) parseHaving() (*WherePart, bool, error) {
 	return t, true, nil
 }
 
+func (p *Parser) parseWindows() ([]*WindowDef, error) {
+	if !p.isWindowClause() {
+		return nil, nil
+	}
+
+	p.next()
+
+	var windows []*WindowDef
+
+	for {
+		t, err := p.accept(IDENT)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := p.accept(AS); err != nil {
+			return nil, err
+		}
+
+		over, err := p.parseWindowSpec()
+		if err != nil {
+			return nil, err
+		}
+
+		windows = append(windows, &WindowDef{Pos: t.Pos, Name: t.Str, Over: over})
+
+		if p.peek().Type != COMMA {
+			break
+		}
+		p.next()
+	}
+
+	return windows, nil
+}
+
 func (p *Parser) parseHavingPart() (*WherePart, error) {
 	exp, err := p.parseBooleanExpr()
 	if err != nil {
@@ -1606,8 +2301,22 @@ func (p *Parser) parseFrom() ([]SqlFrom, error) {
 
 	for {
 		t = p.peek()
-		// is a subquery
-		if t.Type == LPAREN {
+		if t.Type == LPAREN && p.peekTwo().Type == SELECT {
+			d, err := p.parseDerivedTable()
+			if err != nil {
+				return nil, err
+			}
+
+			if p.isJoin() {
+				joins, err := p.parseJoins()
+				if err != nil {
+					return nil, err
+				}
+				d.Joins = joins
+			}
+
+			froms = append(froms, d)
+		} else if t.Type == LPAREN {
 			sel, err := p.parseParenExpr()
 			if err != nil {
 				return nil, err
@@ -1622,7 +2331,7 @@ func (p *Parser) parseFrom() ([]SqlFrom, error) {
 				default:
 					return nil, newError(t, "Expecting alias, got %s", t.Str)
 				}
-			} else if p.peek().Type == IDENT {
+			} else if p.isImplicitAlias() {
 				t, err = p.accept(IDENT)
 				if err != nil {
 					return nil, err
@@ -1671,6 +2380,9 @@ func (p *Parser) parseFromTable(t *Token) (*Table, error) {
 			return nil, newError(t, "Expecting alias, got %s", t.Str)
 		}
 	case IDENT:
+		if !p.isImplicitAlias() {
+			break
+		}
 		t, err = p.accept(IDENT)
 		if err != nil {
 			return nil, err
@@ -1678,8 +2390,7 @@ func (p *Parser) parseFromTable(t *Token) (*Table, error) {
 		table.Alias = t.Str
 	}
 
-	switch p.peek().Type {
-	case LEFT, RIGHT, INNER, OUTER, CROSS, JOIN:
+	if p.isJoin() {
 		joins, err := p.parseJoins()
 		if err != nil {
 			return nil, err
@@ -1696,12 +2407,34 @@ func (p *Parser) parseJoins() ([]*Join, error) {
 loop:
 	for {
 		var tp Type
+		var natural bool
 
 		t := p.peek()
-		switch t.Type {
-		case LEFT, RIGHT, INNER, OUTER, CROSS:
-			tp = t.Type
+		if p.isNaturalJoin() {
+			natural = true
 			p.next()
+
+			switch p.peek().Type {
+			case LEFT, RIGHT, INNER, JOIN:
+			default:
+				if !p.isFullJoin() {
+					k := p.peek()
+					return nil, newError(k, "Unexpected %s after NATURAL", k.Str)
+				}
+			}
+		}
+
+		switch p.peek().Type {
+		case LEFT, RIGHT:
+			tp = p.next().Type
+			if p.peek().Type == OUTER {
+				p.next()
+			}
+			if _, err := p.accept(JOIN); err != nil {
+				return nil, err
+			}
+		case INNER, OUTER, CROSS:
+			tp = p.next().Type
 			if _, err := p.accept(JOIN); err != nil {
 				return nil, err
 			}
@@ -1709,7 +2442,33 @@ loop:
 			tp = JOIN
 			p.next()
 		default:
-			break loop
+			if !p.isFullJoin() {
+				break loop
+			}
+			p.next()
+			tp = FULL
+			if p.peek().Type == OUTER {
+				p.next()
+			}
+			if _, err := p.accept(JOIN); err != nil {
+				return nil, err
+			}
+		}
+
+		if p.peek().Type == LPAREN {
+			d, err := p.parseDerivedTable()
+			if err != nil {
+				return nil, err
+			}
+
+			join := &Join{Pos: t.Pos, Type: tp, Natural: natural, Subquery: d}
+
+			if err := p.parseJoinCondition(join); err != nil {
+				return nil, err
+			}
+
+			joins = append(joins, join)
+			continue
 		}
 
 		db, tableName, err := p.parseSelectorIdent()
@@ -1717,7 +2476,7 @@ loop:
 			return nil, err
 		}
 
-		join := &Join{Pos: t.Pos, Database: db, Table: tableName, Type: tp}
+		join := &Join{Pos: t.Pos, Database: db, Table: tableName, Type: tp, Natural: natural}
 
 		// parse the alias part
 		switch p.peek().Type {
@@ -1732,6 +2491,9 @@ loop:
 			}
 
 		case IDENT:
+			if !p.isImplicitAlias() {
+				break
+			}
 			t, err = p.accept(IDENT)
 			if err != nil {
 				return nil, err
@@ -1739,13 +2501,8 @@ loop:
 			join.Alias = t.Str
 		}
 
-		if p.peek().Type == ON {
-			p.next()
-			exp, err := p.parseBooleanExpr()
-			if err != nil {
-				return nil, err
-			}
-			join.On = exp
+		if err := p.parseJoinCondition(join); err != nil {
+			return nil, err
 		}
 
 		joins = append(joins, join)
@@ -1754,88 +2511,232 @@ loop:
 	return joins, nil
 }
 
-// parses a 'name' or a 'name.selector' expression
-func (p *Parser) parseSelectorIdent() (string, string, error) {
-	a, err := p.parsePrefixedIdent()
-	if err != nil {
-		return "", "", err
-	}
-
-	if p.peek().Type == PERIOD {
-		p.next()
-		b, err := p.parsePrefixedIdent()
-		if err != nil {
-			return "", "", err
-		}
-		return a, b, nil
+// isJoin returns true if the next token starts a join.
+func (p *Parser) isJoin() bool {
+	switch p.peek().Type {
+	case LEFT, RIGHT, INNER, OUTER, CROSS, JOIN:
+		return true
 	}
-
-	return "", a, nil
+	return p.isFullJoin() || p.isNaturalJoin()
 }
 
-// an identifier that can have a prefix separated with :
-// for example crm:client
-func (p *Parser) parsePrefixedIdent() (string, error) {
-	a, err := p.accept(IDENT)
-	if err != nil {
-		return "", err
+// isNaturalJoin returns true if the next tokens are NATURAL and a join.
+// NATURAL is not a reserved word so it can also be an alias.
+func (p *Parser) isNaturalJoin() bool {
+	if !isWord(p.peek(), "NATURAL") {
+		return false
 	}
 
-	ident := a.Str
-
-	for {
-		if p.peek().Type == COLON {
-			p.next()
-			aa, err := p.accept(IDENT)
-			if err != nil {
-				return "", err
-			}
-			ident += ":" + aa.Str
-		} else {
-			break
-		}
+	// CROSS is invalid but it is a join so it can't be an alias.
+	t := p.peekTwo()
+	switch t.Type {
+	case LEFT, RIGHT, INNER, OUTER, CROSS, JOIN:
+		return true
 	}
+	return isWord(t, "FULL")
+}
 
-	return ident, nil
+// isUsing returns true if the next tokens are USING (.
+// USING is not a reserved word so it can also be an alias.
+func (p *Parser) isUsing() bool {
+	return isWord(p.peek(), "USING") && p.peekTwo().Type == LPAREN
 }
 
-func (p *Parser) parseColumnNameExpr() (*ColumnNameExpr, error) {
+// isFullJoin returns true if the next tokens are FULL [OUTER] JOIN.
+// FULL is not a reserved word so it can also be an alias.
+func (p *Parser) isFullJoin() bool {
 	t := p.peek()
-
-	table, name, err := p.parseSelectorIdent()
-	if err != nil {
-		return nil, err
+	if t.Type != IDENT || !strings.EqualFold(t.Str, "FULL") {
+		return false
 	}
 
-	return &ColumnNameExpr{Pos: t.Pos, Table: table, Name: name}, nil
+	switch p.peekTwo().Type {
+	case JOIN, OUTER:
+		return true
+	}
+	return false
 }
 
-func (p *Parser) parseColumnExpr() (Expr, error) {
-	t := p.peek()
-
-	table, name, err := p.parseSelecColumnIdent()
-	if err != nil {
-		return nil, err
+// isImplicitAlias returns true if the next token is an alias without AS.
+// The words that are not reserved are not an alias where they start a clause.
+func (p *Parser) isImplicitAlias() bool {
+	if p.peek().Type != IDENT {
+		return false
 	}
-
-	if name == "*" {
-		return &AllColumnsExpr{Pos: t.Pos, Table: table}, nil
+	switch {
+	case p.isFullJoin(), p.isNaturalJoin(), p.isUsing(), p.isWindowClause():
+		return false
 	}
-
-	return &ColumnNameExpr{Pos: t.Pos, Table: table, Name: name}, nil
+	return p.setOperation() == NOTSET
 }
 
-// parses a select column expression like: 'name', 'a.*' or 'a.name'
-func (p *Parser) parseSelecColumnIdent() (string, string, error) {
-	a, err := p.accept(IDENT)
-	if err != nil {
-		return "", "", err
-	}
+// isWindowClause returns true if the next tokens are WINDOW name AS.
+// WINDOW is not a reserved word so it can also be a column or an alias.
+func (p *Parser) isWindowClause() bool {
+	return isWord(p.peek(), "WINDOW") && p.peekTwo().Type == IDENT && p.peekAt(2).Type == AS
+}
 
-	if p.peek().Type == PERIOD {
+// parses the optional ON expr or USING (col, ...) of a join
+func (p *Parser) parseJoinCondition(join *Join) error {
+	switch {
+	case p.peek().Type == ON:
 		p.next()
-		if p.peek().Type == MUL {
-			p.next()
+		exp, err := p.parseBooleanExpr()
+		if err != nil {
+			return err
+		}
+		join.On = exp
+
+	case p.isUsing():
+		p.next()
+		if _, err := p.accept(LPAREN); err != nil {
+			return err
+		}
+
+		for {
+			t, err := p.accept(IDENT)
+			if err != nil {
+				return err
+			}
+			join.Using = append(join.Using, t.Str)
+
+			if p.peek().Type != COMMA {
+				break
+			}
+			p.next()
+		}
+
+		if _, err := p.accept(RPAREN); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parses a subquery with an optional alias: (SELECT ...) [AS] name
+func (p *Parser) parseDerivedTable() (*DerivedTable, error) {
+	t, err := p.accept(LPAREN)
+	if err != nil {
+		return nil, err
+	}
+
+	sel, err := p.parseSelect()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.accept(RPAREN); err != nil {
+		return nil, err
+	}
+
+	d := &DerivedTable{Pos: t.Pos, Query: sel}
+
+	switch p.peek().Type {
+	case AS:
+		p.next()
+		t = p.next()
+		switch t.Type {
+		case IDENT, STRING, TEXT:
+			d.Alias = t.Str
+		default:
+			return nil, newError(t, "Expecting alias, got %s", t.Str)
+		}
+	case IDENT:
+		if p.isImplicitAlias() {
+			d.Alias = p.next().Str
+		}
+	}
+
+	return d, nil
+}
+
+// parses a 'name' or a 'name.selector' expression
+func (p *Parser) parseSelectorIdent() (string, string, error) {
+	a, err := p.parsePrefixedIdent()
+	if err != nil {
+		return "", "", err
+	}
+
+	if p.peek().Type == PERIOD {
+		p.next()
+		b, err := p.parsePrefixedIdent()
+		if err != nil {
+			return "", "", err
+		}
+		return a, b, nil
+	}
+
+	return "", a, nil
+}
+
+// an identifier that can have a prefix separated with :
+// for example crm:client
+func (p *Parser) parsePrefixedIdent() (string, error) {
+	a, err := p.accept(IDENT)
+	if err != nil {
+		return "", err
+	}
+
+	ident := a.Str
+
+	for {
+		if p.peek().Type == COLON {
+			p.next()
+			aa, err := p.accept(IDENT)
+			if err != nil {
+				return "", err
+			}
+			ident += ":" + aa.Str
+		} else {
+			break
+		}
+	}
+
+	return ident, nil
+}
+
+func (p *Parser) parseColumnNameExpr() (*ColumnNameExpr, error) {
+	t := p.peek()
+
+	table, name, err := p.parseSelectorIdent()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ColumnNameExpr{Pos: t.Pos, Table: table, Name: name}, nil
+}
+
+func (p *Parser) parseColumnExpr() (Expr, error) {
+	t := p.peek()
+
+	table, name, err := p.parseSelecColumnIdent()
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "*" {
+		return &AllColumnsExpr{Pos: t.Pos, Table: table}, nil
+	}
+
+	if p.upsert && strings.EqualFold(table, "excluded") {
+		return &ExcludedExpr{Pos: t.Pos, Name: name}, nil
+	}
+
+	return &ColumnNameExpr{Pos: t.Pos, Table: table, Name: name}, nil
+}
+
+// parses a select column expression like: 'name', 'a.*' or 'a.name'
+func (p *Parser) parseSelecColumnIdent() (string, string, error) {
+	a, err := p.accept(IDENT)
+	if err != nil {
+		return "", "", err
+	}
+
+	if p.peek().Type == PERIOD {
+		p.next()
+		if p.peek().Type == MUL {
+			p.next()
 			return a.Str, "*", nil
 		}
 
@@ -1913,6 +2814,18 @@ func (p *Parser) parseNotFactor() (Expr, error) {
 			return nil, err
 		}
 		return &UnaryExpr{Pos: t.Pos, Operator: t.Type, Operand: exp}, nil
+
+	case NOT:
+		if p.peekTwo().Type == EXISTS {
+			p.next()
+			e, err := p.parseExistsExpr()
+			if err != nil {
+				return nil, err
+			}
+			e.Pos = t.Pos
+			e.Not = true
+			return e, nil
+		}
 	}
 
 	return p.parseRelation()
@@ -1932,10 +2845,9 @@ loop:
 		case NOT:
 			p.next()
 			t = p.peek()
-			switch t.Type {
-			case LIKE:
-				p.next()
-				rh, err := p.parseExpr()
+
+			if op := matchOperator(t); op != 0 {
+				rh, err := p.parseMatchPattern(op)
 				if err != nil {
 					return nil, err
 				}
@@ -1943,8 +2855,12 @@ loop:
 				e = &BinaryExpr{
 					Left:     e,
 					Right:    rh,
-					Operator: NOTLIKE,
+					Operator: notMatchOperator(op),
 				}
+				continue
+			}
+
+			switch t.Type {
 			case IN:
 				rh, err := p.parseInExpr(e)
 				if err != nil {
@@ -1959,17 +2875,38 @@ loop:
 			default:
 				return nil, newError(t, "Unexpected %s after NOT", t.Str)
 			}
-		case EQL, NEQ, LSS, LEQ, GTR, GEQ, LIKE:
+		case LIKE, IDENT:
+			op := matchOperator(t)
+			if op == 0 {
+				break loop
+			}
+
+			rh, err := p.parseMatchPattern(op)
+			if err != nil {
+				return nil, err
+			}
+
+			e = &BinaryExpr{
+				Left:     e,
+				Right:    rh,
+				Operator: op,
+			}
+		case EQL, NEQ, LSS, LEQ, GTR, GEQ, NSEQ:
 			p.next()
 			rh, err := p.parseExpr()
 			if err != nil {
 				return nil, err
 			}
 
+			op := t.Type
+			if op == NSEQ {
+				op = ISNOTDISTINCT
+			}
+
 			e = &BinaryExpr{
 				Left:     e,
 				Right:    rh,
-				Operator: t.Type,
+				Operator: op,
 			}
 		case IS:
 			p.next()
@@ -1980,6 +2917,19 @@ loop:
 				p.next()
 			}
 
+			if p.peek().Type == DISTINCT {
+				p.next()
+				if _, err := p.accept(FROM); err != nil {
+					return nil, err
+				}
+
+				if tp == IS {
+					tp = ISDISTINCT
+				} else {
+					tp = ISNOTDISTINCT
+				}
+			}
+
 			rh, err := p.parseExpr()
 			if err != nil {
 				return nil, err
@@ -2020,6 +2970,65 @@ loop:
 	return e, nil
 }
 
+// matchOperator returns LIKE, ILIKE, REGEXP or GLOB if the token
+// is one of them or 0. RLIKE is the same as REGEXP.
+func matchOperator(t *Token) Type {
+	if t.Type == LIKE {
+		return LIKE
+	}
+
+	if t.Type != IDENT {
+		return 0
+	}
+
+	switch strings.ToUpper(t.Str) {
+	case "ILIKE":
+		return ILIKE
+	case "REGEXP", "RLIKE":
+		return REGEXP
+	case "GLOB":
+		return GLOB
+	default:
+		return 0
+	}
+}
+
+func notMatchOperator(op Type) Type {
+	switch op {
+	case LIKE:
+		return NOTLIKE
+	case ILIKE:
+		return NOTILIKE
+	case REGEXP:
+		return NOTREGEXP
+	default:
+		return NOTGLOB
+	}
+}
+
+// parseMatchPattern parses the pattern after a match operator.
+// LIKE and ILIKE can have an ESCAPE character.
+func (p *Parser) parseMatchPattern(op Type) (Expr, error) {
+	p.next()
+
+	pattern, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	if (op == LIKE || op == ILIKE) && t.Type == IDENT && strings.EqualFold(t.Str, "ESCAPE") {
+		p.next()
+		escape, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		return &EscapeExpr{Pos: t.Pos, Pattern: pattern, Escape: escape}, nil
+	}
+
+	return pattern, nil
+}
+
 func (p *Parser) parseBetweenExpr(e Expr) (*BetweenExpr, error) {
 	p.next()
 
@@ -2078,57 +3087,51 @@ func (p *Parser) parseInExpr(e Expr) (*InExpr, error) {
 	return &InExpr{LParen: lp.Pos, Values: values, RParen: rp.Pos}, nil
 }
 
-func (p *Parser) parseExpr() (Expr, error) {
-	lh, err := p.parseTerm()
-	if err != nil {
-		return nil, err
-	}
-
-	var e Expr = lh
+// termLevels are the arithmetic and bitwise operators from the lowest
+// precedence to the highest as in MySQL. || binds tighter than * as in
+// SQLite and MySQL with PIPES_AS_CONCAT.
+var termLevels = [][]Type{
+	{ORB},
+	{ANB},
+	{LSF, RSF},
+	{ADD, SUB},
+	{MUL, DIV, MOD},
+	{XOB},
+	{CONCAT},
+}
 
-loop:
-	for {
-		t := p.peek()
-		switch t.Type {
-		case ADD, SUB:
-			p.next()
-			rh, err := p.parseTerm()
-			if err != nil {
-				return nil, err
-			}
+func (p *Parser) parseExpr() (Expr, error) {
+	return p.parseTerm(0)
+}
 
-			e = &BinaryExpr{Left: e, Right: rh, Operator: t.Type}
-		default:
-			break loop
-		}
+// parseTerm parses the operators of a level of termLevels and the ones
+// with higher precedence. All of them are left associative.
+func (p *Parser) parseTerm(level int) (Expr, error) {
+	if level == len(termLevels) {
+		return p.parseSignedFactor()
 	}
 
-	return e, nil
-}
-
-func (p *Parser) parseTerm() (Expr, error) {
-	lh, err := p.parseSignedFactor()
+	e, err := p.parseTerm(level + 1)
 	if err != nil {
 		return nil, err
 	}
 
-	var e Expr = lh
-
 loop:
 	for {
 		t := p.peek()
-		switch t.Type {
-		case MUL, DIV, MOD, LSF, ANB:
-			p.next()
-			rh, err := p.parseSignedFactor()
-			if err != nil {
-				return nil, err
-			}
+		for _, op := range termLevels[level] {
+			if t.Type == op {
+				p.next()
+				rh, err := p.parseTerm(level + 1)
+				if err != nil {
+					return nil, err
+				}
 
-			e = &BinaryExpr{Left: e, Right: rh, Operator: t.Type}
-		default:
-			break loop
+				e = &BinaryExpr{Left: e, Right: rh, Operator: t.Type}
+				continue loop
+			}
 		}
+		break
 	}
 
 	return e, nil
@@ -2137,9 +3140,16 @@ loop:
 func (p *Parser) parseSignedFactor() (Expr, error) {
 	t := p.peek()
 	switch t.Type {
-	case ADD, SUB:
+	case ADD, SUB, NTB:
 		p.next()
-		exp, err := p.parseFactor()
+
+		// a negative number is a constant
+		if n := p.peek(); t.Type == SUB && (n.Type == INT || n.Type == FLOAT) {
+			p.next()
+			return p.constant(n, "-"+n.Str)
+		}
+
+		exp, err := p.parseSignedFactor()
 		if err != nil {
 			return nil, err
 		}
@@ -2155,6 +3165,13 @@ func parseValue(t Type, s string) (interface{}, error) {
 		return strconv.Atoi(s)
 	case FLOAT:
 		return strconv.ParseFloat(s, 64)
+	case HEX, BIT:
+		return parseBinary(t, s)
+	case HEXSTRING:
+		if s == "" {
+			return []byte{}, nil
+		}
+		return hex.DecodeString(s)
 	case STRING:
 		return s, nil
 	case NULL:
@@ -2168,20 +3185,49 @@ func parseValue(t Type, s string) (interface{}, error) {
 	}
 }
 
+// parseBinary returns a HEX or BIT constant as an int64 or as an uint64
+// if it doesn't fit. An empty BIT is an empty binary string.
+func parseBinary(t Type, s string) (interface{}, error) {
+	if s == "" {
+		return []byte{}, nil
+	}
+
+	base := 16
+	if t == BIT {
+		base = 2
+	}
+
+	v, err := strconv.ParseUint(s, base, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	if v > math.MaxInt64 {
+		return v, nil
+	}
+	return int64(v), nil
+}
+
+// constant returns the value of the token or a parameter
+// if ReplaceParams is set.
+func (p *Parser) constant(t *Token, value string) (Expr, error) {
+	if p.ReplaceParams {
+		v, err := parseValue(t.Type, value)
+		if err != nil {
+			return nil, newError(t, err.Error())
+		}
+		p.Params = append(p.Params, v)
+		return &ParameterExpr{t.Pos, ""}, nil
+	}
+	return &ConstantExpr{t.Pos, t.Type, value}, nil
+}
+
 func (p *Parser) parseFactor() (Expr, error) {
 	t := p.peek()
 	switch t.Type {
-	case INT, FLOAT, STRING, NULL, TRUE, FALSE:
+	case INT, FLOAT, STRING, HEX, BIT, HEXSTRING, NULL, TRUE, FALSE:
 		p.next()
-		if p.ReplaceParams {
-			v, err := parseValue(t.Type, t.Str)
-			if err != nil {
-				return nil, newError(t, err.Error())
-			}
-			p.Params = append(p.Params, v)
-			return &ParameterExpr{t.Pos, ""}, nil
-		}
-		return &ConstantExpr{t.Pos, t.Type, t.Str}, nil
+		return p.constant(t, t.Str)
 
 	case DEFAULT:
 		p.next()
@@ -2195,11 +3241,20 @@ func (p *Parser) parseFactor() (Expr, error) {
 		p.next()
 		return &ParameterExpr{t.Pos, ""}, nil
 
+	case NAMEDPARAM:
+		p.next()
+		return &ParameterExpr{t.Pos, t.Str[1:]}, nil
+
 	case IDENT, DISTINCT:
+		if p.isCaseExpr() {
+			return p.parseCaseExpr()
+		}
 		if p.peekTwo().Type == LPAREN {
 			switch strings.ToUpper(t.Str) {
 			case "GROUP_CONCAT":
 				return p.parseGroupConcat()
+			case "CAST", "CONVERT":
+				return p.parseCastExpr()
 			default:
 				return p.parseCallExpr()
 			}
@@ -2209,11 +3264,143 @@ func (p *Parser) parseFactor() (Expr, error) {
 	case LPAREN:
 		return p.parseParenExpr()
 
+	case EXISTS:
+		return p.parseExistsExpr()
+
+	case VALUES:
+		return p.parseExcludedExpr()
+
 	default:
 		return nil, newError(t, "Expecting expression, got %s", t.Type)
 	}
 }
 
+func (p *Parser) parseExistsExpr() (*ExistsExpr, error) {
+	t, err := p.accept(EXISTS)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.accept(LPAREN); err != nil {
+		return nil, err
+	}
+
+	s, err := p.parseSelect()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.accept(RPAREN); err != nil {
+		return nil, err
+	}
+
+	return &ExistsExpr{Pos: t.Pos, Query: s}, nil
+}
+
+// isCaseExpr returns true if the next tokens are a CASE expression.
+// CASE is not a reserved word so it is a column unless a WHEN follows
+// it directly or after the value of the simple form.
+func (p *Parser) isCaseExpr() bool {
+	if !isWord(p.peek(), "CASE") {
+		return false
+	}
+
+	t := p.peekTwo()
+	if isWord(t, "WHEN") || isWord(t, "END") {
+		return true
+	}
+
+	depth := 0
+	for i := 1; ; i++ {
+		t := p.peekAt(i)
+		switch t.Type {
+		case EOF, SEMICOLON:
+			return false
+		case LPAREN:
+			depth++
+			continue
+		case RPAREN:
+			if depth == 0 {
+				return false
+			}
+			depth--
+			continue
+		}
+
+		if depth > 0 {
+			continue
+		}
+
+		switch t.Type {
+		case COMMA, SELECT, FROM, WHERE, GROUP, HAVING, ORDER, LIMIT, UNION, AS, ON:
+			return false
+		case IDENT:
+			switch strings.ToUpper(t.Str) {
+			case "WHEN":
+				return true
+			case "CASE", "THEN", "ELSE", "END":
+				return false
+			}
+		}
+	}
+}
+
+func (p *Parser) parseCaseExpr() (*CaseExpr, error) {
+	t, err := p.acceptString("CASE")
+	if err != nil {
+		return nil, err
+	}
+
+	c := &CaseExpr{Pos: t.Pos}
+
+	// the simple form has a value to compare with each WHEN
+	if !isWord(p.peek(), "WHEN") {
+		c.Value, err = p.parseBooleanExpr()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for isWord(p.peek(), "WHEN") {
+		t = p.next()
+
+		cond, err := p.parseBooleanExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := p.acceptString("THEN"); err != nil {
+			return nil, err
+		}
+
+		result, err := p.parseBooleanExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		c.Whens = append(c.Whens, &WhenClause{Pos: t.Pos, Cond: cond, Result: result})
+	}
+
+	if len(c.Whens) == 0 {
+		t = p.peek()
+		return nil, newError(t, "Expecting WHEN, got %s", t.Str)
+	}
+
+	if isWord(p.peek(), "ELSE") {
+		p.next()
+		c.Else, err = p.parseBooleanExpr()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.acceptString("END"); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
 func (p *Parser) parseParenExpr() (*ParenExpr, error) {
 	lparen, err := p.accept(LPAREN)
 	if err != nil {
@@ -2329,9 +3516,214 @@ func (p *Parser) parseCallExpr() (*CallExpr, error) {
 
 	call := &CallExpr{Pos: t.Pos, Name: t.Str, Args: args}
 
+	if isWord(p.peek(), "OVER") {
+		over, err := p.parseOver()
+		if err != nil {
+			return nil, err
+		}
+		call.Over = over
+	}
+
 	return call, nil
 }
 
+// parses CAST(expr AS type) or CONVERT(expr, type)
+func (p *Parser) parseCastExpr() (*CastExpr, error) {
+	t := p.next()
+
+	if _, err := p.accept(LPAREN); err != nil {
+		return nil, err
+	}
+
+	exp, err := p.parseBooleanExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(t.Str, "CAST") {
+		_, err = p.accept(AS)
+	} else {
+		_, err = p.accept(COMMA)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &CreateColumn{}
+
+	// the types that are only valid in a cast
+	k := p.peek()
+	switch strings.ToUpper(k.Str) {
+	case "SIGNED", "UNSIGNED":
+		p.next()
+		c.Type = Int
+		c.Unsigned = strings.EqualFold(k.Str, "UNSIGNED")
+		if k := p.peek(); k.Type == INTEGER || strings.EqualFold(k.Str, "INTEGER") {
+			p.next()
+		}
+	case "INTEGER":
+		p.next()
+		c.Type = Int
+	case "BINARY":
+		p.next()
+		c.Type = Blob
+	default:
+		if err := p.parseColumnType(c); err != nil {
+			return nil, err
+		}
+		if c.Type == Enum {
+			return nil, newError(k, "Invalid cast type %s", k.Str)
+		}
+	}
+
+	if err := p.parseColumnSize(c); err != nil {
+		return nil, err
+	}
+
+	if _, err := p.accept(RPAREN); err != nil {
+		return nil, err
+	}
+
+	return &CastExpr{Pos: t.Pos, Expr: exp, Type: c.Type, Size: c.Size, Decimals: c.Decimals, Unsigned: c.Unsigned}, nil
+}
+
+// parses OVER name or OVER (window spec)
+func (p *Parser) parseOver() (*OverClause, error) {
+	t, err := p.acceptString("OVER")
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek().Type == IDENT {
+		name := p.next()
+		return &OverClause{Pos: t.Pos, Name: name.Str}, nil
+	}
+
+	return p.parseWindowSpec()
+}
+
+// parses ([name] [PARTITION BY ...] [ORDER BY ...] [frame])
+func (p *Parser) parseWindowSpec() (*OverClause, error) {
+	t, err := p.accept(LPAREN)
+	if err != nil {
+		return nil, err
+	}
+
+	over := &OverClause{Pos: t.Pos}
+
+	t = p.peek()
+	if t.Type == IDENT && !isWindowKeyword(t.Str) {
+		p.next()
+		over.Name = t.Str
+	}
+
+	if strings.EqualFold(p.peek().Str, "PARTITION") {
+		p.next()
+		if _, err := p.accept(BY); err != nil {
+			return nil, err
+		}
+		over.PartitionBy, err = p.parseExpressionList()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	over.OrderByPart, err = p.parseOrderBy()
+	if err != nil {
+		return nil, err
+	}
+
+	t = p.peek()
+	if t.Type == IDENT {
+		switch strings.ToUpper(t.Str) {
+		case "ROWS", "RANGE":
+			p.next()
+			over.Frame, err = p.parseWindowFrame(strings.ToUpper(t.Str))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if _, err := p.accept(RPAREN); err != nil {
+		return nil, err
+	}
+
+	return over, nil
+}
+
+func isWindowKeyword(s string) bool {
+	switch strings.ToUpper(s) {
+	case "PARTITION", "ROWS", "RANGE":
+		return true
+	}
+	return false
+}
+
+func (p *Parser) parseWindowFrame(unit string) (*WindowFrame, error) {
+	f := &WindowFrame{Unit: unit}
+
+	between := p.peek().Type == BETWEEN
+	if between {
+		p.next()
+	}
+
+	start, err := p.parseFrameBound()
+	if err != nil {
+		return nil, err
+	}
+	f.Start = start
+
+	if between {
+		if _, err := p.accept(AND); err != nil {
+			return nil, err
+		}
+		end, err := p.parseFrameBound()
+		if err != nil {
+			return nil, err
+		}
+		f.End = end
+	}
+
+	return f, nil
+}
+
+func (p *Parser) parseFrameBound() (*FrameBound, error) {
+	t := p.peek()
+
+	switch strings.ToUpper(t.Str) {
+	case "UNBOUNDED":
+		p.next()
+		t = p.next()
+		switch strings.ToUpper(t.Str) {
+		case "PRECEDING", "FOLLOWING":
+			return &FrameBound{Type: "UNBOUNDED " + strings.ToUpper(t.Str)}, nil
+		default:
+			return nil, newError(t, "Expecting PRECEDING or FOLLOWING, got %s", t.Str)
+		}
+
+	case "CURRENT":
+		p.next()
+		if _, err := p.acceptString("ROW"); err != nil {
+			return nil, err
+		}
+		return &FrameBound{Type: "CURRENT ROW"}, nil
+	}
+
+	offset, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	t = p.next()
+	switch strings.ToUpper(t.Str) {
+	case "PRECEDING", "FOLLOWING":
+		return &FrameBound{Type: strings.ToUpper(t.Str), Offset: offset}, nil
+	default:
+		return nil, newError(t, "Expecting PRECEDING or FOLLOWING, got %s", t.Str)
+	}
+}
+
 func (p *Parser) parseExpressionList() ([]Expr, error) {
 	var args []Expr
 
@@ -2353,22 +3745,42 @@ func (p *Parser) parseExpressionList() ([]Expr, error) {
 }
 
 func (p *Parser) peek() *Token {
-	if p.lexIdex >= len(p.lexer.Tokens) {
+	i := p.tokenIndex(p.lexIdex)
+	if i >= len(p.lexer.Tokens) {
 		return &Token{Type: EOF}
 	}
-	return p.lexer.Tokens[p.lexIdex]
+	return p.lexer.Tokens[i]
 }
 
 // peek two positions forward
 func (p *Parser) peekTwo() *Token {
-	i := p.lexIdex + 1
+	i := p.tokenIndex(p.tokenIndex(p.lexIdex) + 1)
 	if i >= len(p.lexer.Tokens) {
 		return &Token{Type: EOF}
 	}
 	return p.lexer.Tokens[i]
 }
 
+// peekAt returns the token n positions forward. peekAt(0) is peek().
+func (p *Parser) peekAt(n int) *Token {
+	i := p.tokenIndex(p.lexIdex)
+	for ; n > 0 && i < len(p.lexer.Tokens); n-- {
+		i = p.tokenIndex(i + 1)
+	}
+	if i >= len(p.lexer.Tokens) {
+		return &Token{Type: EOF}
+	}
+	return p.lexer.Tokens[i]
+}
+
+// isWord returns true if t is the identifier s. It is used for the
+// words that are not reserved and only are keywords in some places.
+func isWord(t *Token, s string) bool {
+	return t.Type == IDENT && strings.EqualFold(t.Str, s)
+}
+
 func (p *Parser) next() *Token {
+	p.lexIdex = p.tokenIndex(p.lexIdex)
 	if p.lexIdex >= len(p.lexer.Tokens) {
 		return &Token{Type: EOF}
 	}
@@ -2377,6 +3789,38 @@ func (p *Parser) next() *Token {
 	return t
 }
 
+// tokenIndex returns the index of the first token from i that is not
+// a comment. Only Parse reads the comments to attach them to the queries.
+func (p *Parser) tokenIndex(i int) int {
+	for i < len(p.lexer.Tokens) && p.lexer.Tokens[i].Type == COMMENT {
+		i++
+	}
+	return i
+}
+
+// readComments reads the comments at the current position. If line
+// is not -1 it only reads the ones that start in that line.
+func (p *Parser) readComments(line int) []*Comment {
+	var comments []*Comment
+
+	for p.lexIdex < len(p.lexer.Tokens) {
+		t := p.lexer.Tokens[p.lexIdex]
+		if t.Type != COMMENT {
+			break
+		}
+
+		// the position is the end of the token
+		if line != -1 && t.Pos.Line-strings.Count(t.Str, "\n") != line {
+			break
+		}
+
+		comments = append(comments, &Comment{Pos: t.Pos, Text: t.Str})
+		p.lexIdex++
+	}
+
+	return comments
+}
+
 func (p *Parser) accept(k Type) (*Token, error) {
 	t := p.next()
 	if t.Type != k {
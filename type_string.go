@@ -99,11 +99,39 @@ func _() {
 	_ = x[COLON-88]
 	_ = x[SEMICOLON-89]
 	_ = x[QUESTION-90]
+	_ = x[NAMEDPARAM-91]
+	_ = x[CASE-92]
+	_ = x[WHEN-93]
+	_ = x[THEN-94]
+	_ = x[ELSE-95]
+	_ = x[END-96]
+	_ = x[WITH-97]
+	_ = x[OVER-98]
+	_ = x[WINDOW-99]
+	_ = x[INTERSECT-100]
+	_ = x[EXCEPT-101]
+	_ = x[FULL-102]
+	_ = x[HEX-103]
+	_ = x[BIT-104]
+	_ = x[HEXSTRING-105]
+	_ = x[ORB-106]
+	_ = x[XOB-107]
+	_ = x[NTB-108]
+	_ = x[CONCAT-109]
+	_ = x[ILIKE-110]
+	_ = x[NOTILIKE-111]
+	_ = x[REGEXP-112]
+	_ = x[NOTREGEXP-113]
+	_ = x[GLOB-114]
+	_ = x[NOTGLOB-115]
+	_ = x[ISDISTINCT-116]
+	_ = x[ISNOTDISTINCT-117]
+	_ = x[NSEQ-118]
 }
 
-const _Type_name = "NOTSETERROREOFCOMMENTCREATESHOWDROPALTERTABLEDATABASENOTEXISTSCONSTRAINTINTEGERDECIMALCHARVARCHARTEXTMEDIUMTEXTBOOLBLOBDATETIMEDEFAULTSELECTDISTINCTINSERTINTOVALUESUPDATESETDELETEFROMWHEREGROUPHAVINGJOINLEFTRIGHTINNEROUTERCROSSONASINNOTINBETWEENLIKEISISNOTNOTLIKEORDERBYASCDESCRANDOMLIMITUNIONANDORNULLTRUEFALSEFORIDENTINTFLOATSTRINGADDSUBMULDIVMODLSFRSFANBEQLLSSGTRNTNEQLEQGEQLPARENLBRACKLBRACECOMMAPERIODRPARENCOLONSEMICOLONQUESTION"
+const _Type_name = "NOTSETERROREOFCOMMENTCREATESHOWDROPALTERTABLEDATABASENOTEXISTSCONSTRAINTINTEGERDECIMALCHARVARCHARTEXTMEDIUMTEXTBOOLBLOBDATETIMEDEFAULTSELECTDISTINCTINSERTINTOVALUESUPDATESETDELETEFROMWHEREGROUPHAVINGJOINLEFTRIGHTINNEROUTERCROSSONASINNOTINBETWEENLIKEISISNOTNOTLIKEORDERBYASCDESCRANDOMLIMITUNIONANDORNULLTRUEFALSEFORIDENTINTFLOATSTRINGADDSUBMULDIVMODLSFRSFANBEQLLSSGTRNTNEQLEQGEQLPARENLBRACKLBRACECOMMAPERIODRPARENCOLONSEMICOLONQUESTIONNAMEDPARAMCASEWHENTHENELSEENDWITHOVERWINDOWINTERSECTEXCEPTFULLHEXBITHEXSTRINGORBXOBNTBCONCATILIKENOTILIKEREGEXPNOTREGEXPGLOBNOTGLOBISDISTINCTISNOTDISTINCTNSEQ"
 
-var _Type_index = [...]uint16{0, 6, 11, 14, 21, 27, 31, 35, 40, 45, 53, 56, 62, 72, 79, 86, 90, 97, 101, 111, 115, 119, 127, 134, 140, 148, 154, 158, 164, 170, 173, 179, 183, 188, 193, 199, 203, 207, 212, 217, 222, 227, 229, 231, 233, 238, 245, 249, 251, 256, 263, 268, 270, 273, 277, 283, 288, 293, 296, 298, 302, 306, 311, 314, 319, 322, 327, 333, 336, 339, 342, 345, 348, 351, 354, 357, 360, 363, 366, 368, 371, 374, 377, 383, 389, 395, 400, 406, 412, 417, 426, 434}
+var _Type_index = [...]uint16{0, 6, 11, 14, 21, 27, 31, 35, 40, 45, 53, 56, 62, 72, 79, 86, 90, 97, 101, 111, 115, 119, 127, 134, 140, 148, 154, 158, 164, 170, 173, 179, 183, 188, 193, 199, 203, 207, 212, 217, 222, 227, 229, 231, 233, 238, 245, 249, 251, 256, 263, 268, 270, 273, 277, 283, 288, 293, 296, 298, 302, 306, 311, 314, 319, 322, 327, 333, 336, 339, 342, 345, 348, 351, 354, 357, 360, 363, 366, 368, 371, 374, 377, 383, 389, 395, 400, 406, 412, 417, 426, 434, 444, 448, 452, 456, 460, 463, 467, 471, 477, 486, 492, 496, 499, 502, 511, 514, 517, 520, 526, 531, 539, 545, 554, 558, 565, 575, 588, 592}
 
 func (i Type) String() string {
 	if i >= Type(len(_Type_index)-1) {
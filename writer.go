@@ -1,11 +1,13 @@
 // Package goql is a sql parser.
 //
-//It supports mysql and sqlite3.
+// It supports mysql, sqlite3 and postgres. Other databases can be
+// supported registering a Dialect.
 package goql
 
 import (
 	"bytes"
 	"fmt"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
@@ -34,9 +36,16 @@ type writer struct {
 	Format       bool
 	EscapeIdents bool
 
+	// Comments writes the leading and trailing comments of the query.
+	Comments bool
+
 	// whitelist of allowed functions. It it is nil everything allowed.
 	WhitelistFuncs []string
 
+	// Dialect writes the parts of the query that are specific to each
+	// database. By default it is the one registered for the driver.
+	Dialect Dialect
+
 	buf    *bytes.Buffer
 	params []interface{}
 	driver string
@@ -46,6 +55,15 @@ type writer struct {
 
 	// the number of ? writen
 	paramSymbolCount int
+
+	// the names of the common table expressions in scope.
+	localTables []string
+
+	// if it is writing the assignments of an upsert.
+	upsert bool
+
+	// the values of the named parameters.
+	named interface{}
 }
 
 func NewWriter(q Query, params []interface{}, database, driver string) *writer {
@@ -53,6 +71,8 @@ func NewWriter(q Query, params []interface{}, database, driver string) *writer {
 		driver = "mysql"
 	}
 
+	dialect, _ := GetDialect(driver)
+
 	return &writer{
 		buf:          new(bytes.Buffer),
 		query:        q,
@@ -60,11 +80,13 @@ func NewWriter(q Query, params []interface{}, database, driver string) *writer {
 		params:       params,
 		Database:     database,
 		driver:       driver,
+		Dialect:      dialect,
 		EscapeIdents: true,
 	}
 }
 
-// ToSql returns the parsed query
+// ToSql returns the parsed query. If the query has named parameters
+// the only param must be a map or a struct with their values.
 func ToSql(q Query, params []interface{}, database, driver string) (string, []interface{}, error) {
 	return NewWriter(q, params, database, driver).Write()
 }
@@ -74,9 +96,21 @@ func (p *writer) Write() (string, []interface{}, error) {
 		return "", nil, err
 	}
 
-	switch t := p.query.(type) {
-	case nil:
+	if p.Dialect == nil {
+		return "", nil, fmt.Errorf("Invalid driver %s", p.driver)
+	}
+
+	if p.query == nil {
 		return "", nil, fmt.Errorf("Empty query")
+	}
+
+	if p.Comments {
+		if err := p.writeComments(p.query.QueryComments().Leading, true); err != nil {
+			return "", nil, err
+		}
+	}
+
+	switch t := p.query.(type) {
 	case *SelectQuery:
 		err := p.writeSelect(t)
 		if err != nil {
@@ -137,6 +171,16 @@ func (p *writer) Write() (string, []interface{}, error) {
 		if err != nil {
 			return "", nil, err
 		}
+	case *CreateIndexQuery:
+		err := p.writeCreateIndex(t)
+		if err != nil {
+			return "", nil, err
+		}
+	case *DropIndexQuery:
+		err := p.writeDropIndex(t)
+		if err != nil {
+			return "", nil, err
+		}
 	case *AlterDropQuery:
 		err := p.writeAlterDropQuery(t)
 		if err != nil {
@@ -156,9 +200,75 @@ func (p *writer) Write() (string, []interface{}, error) {
 		panic(fmt.Sprintf("not implemented %T", t))
 	}
 
+	if p.Comments {
+		if err := p.writeComments(p.query.QueryComments().Trailing, false); err != nil {
+			return "", nil, err
+		}
+	}
+
 	return p.buf.String(), p.params, nil
 }
 
+// writeComments writes the leading comments each in its own line or
+// the trailing ones after the query. Line comments end with a new line
+// so nothing written after them is commented.
+func (p *writer) writeComments(comments []*Comment, leading bool) error {
+	for _, c := range comments {
+		s, line, err := commentString(c.Text)
+		if err != nil {
+			return err
+		}
+
+		if !leading {
+			if b := p.buf.Bytes(); len(b) > 0 && b[len(b)-1] != '\n' {
+				p.buf.WriteString(" ")
+			}
+		}
+
+		p.buf.WriteString(s)
+
+		if leading || line {
+			p.buf.WriteString("\n")
+		}
+	}
+
+	return nil
+}
+
+// commentString validates that a comment can't end before its text does.
+// Line comments are written with -- that all databases support followed
+// by a space as MySQL requires. MySQL executes the text of /*! */ and
+// reads hints in /*+ */ so they are not comments and are rejected.
+func commentString(text string) (s string, line bool, err error) {
+	var body string
+
+	switch {
+	case strings.HasPrefix(text, "/*!"), strings.HasPrefix(text, "/*+"):
+		return "", false, fmt.Errorf("Invalid comment: %s", text)
+	case strings.HasPrefix(text, "/*"):
+		if len(text) < 4 || !strings.HasSuffix(text, "*/") || strings.Contains(text[2:len(text)-2], "*/") {
+			return "", false, fmt.Errorf("Invalid comment: %s", text)
+		}
+		return text, false, nil
+	case strings.HasPrefix(text, "--"):
+		body = text[2:]
+	case strings.HasPrefix(text, "#"):
+		body = text[1:]
+	default:
+		return "", false, fmt.Errorf("Invalid comment: %s", text)
+	}
+
+	if strings.ContainsAny(body, "\r\n") {
+		return "", false, fmt.Errorf("Invalid comment: %s", text)
+	}
+
+	if body != "" && body[0] != ' ' && body[0] != '\t' {
+		body = " " + body
+	}
+
+	return "--" + body, true, nil
+}
+
 func (p *writer) writeRenameColumnQuery(q *RenameColumnQuery) error {
 	p.buf.WriteString("ALTER TABLE ")
 
@@ -166,6 +276,16 @@ func (p *writer) writeRenameColumnQuery(q *RenameColumnQuery) error {
 		return err
 	}
 
+	p.currentQuery = q
+
+	if !p.Dialect.Supports(ChangeColumn) {
+		if q.Name == q.Column.Name {
+			p.buf.WriteString(" ")
+			return p.writeAlterColumn(q.Column)
+		}
+		return p.writeRenameColumn(q.Name, q.Column)
+	}
+
 	p.buf.WriteString(" CHANGE ")
 
 	if err := p.writeIdentifier(q.Name); err != nil {
@@ -174,10 +294,88 @@ func (p *writer) writeRenameColumnQuery(q *RenameColumnQuery) error {
 
 	p.buf.WriteString(" ")
 
-	p.currentQuery = q
 	return p.writeCreateColumn(q.Column)
 }
 
+// writeRenameColumn writes RENAME COLUMN for the databases that don't
+// support CHANGE. The definition is validated but it can't be modified
+// in the same query.
+func (p *writer) writeRenameColumn(name string, c *CreateColumn) error {
+	if _, err := p.columnType(c); err != nil {
+		return err
+	}
+
+	p.buf.WriteString(" RENAME COLUMN ")
+
+	if err := p.writeIdentifier(name); err != nil {
+		return err
+	}
+
+	p.buf.WriteString(" TO ")
+
+	return p.writeIdentifier(c.Name)
+}
+
+// writeAlterColumn writes MODIFY as ALTER COLUMN actions for the databases
+// that don't support it. They set the type, NULL and the default value,
+// that is dropped if it has none as MODIFY does.
+func (p *writer) writeAlterColumn(c *CreateColumn) error {
+	if !p.Dialect.Supports(AlterColumn) {
+		return fmt.Errorf("Invalid operation: MODIFY not supported in %s", p.driver)
+	}
+
+	if c.Key || c.AutoIncrement || c.PrimaryKey || c.Generated != nil ||
+		c.OnUpdate != nil || c.Check != nil || c.Type == Enum {
+		return fmt.Errorf("Only the type, NULL and DEFAULT can be modified in %s: %s", p.driver, c.Name)
+	}
+
+	typ, err := p.columnType(c)
+	if err != nil {
+		return err
+	}
+
+	writeAction := func() error {
+		p.buf.WriteString("ALTER COLUMN ")
+		return p.writeIdentifier(c.Name)
+	}
+
+	if err := writeAction(); err != nil {
+		return err
+	}
+
+	p.buf.WriteString(" TYPE ")
+	p.buf.WriteString(typ)
+
+	if collation := p.Dialect.ColumnCollation(c); collation != "" {
+		p.buf.WriteString(" COLLATE ")
+		p.buf.WriteString(collation)
+	}
+
+	p.buf.WriteString(", ")
+	if err := writeAction(); err != nil {
+		return err
+	}
+
+	if c.Nullable {
+		p.buf.WriteString(" DROP NOT NULL")
+	} else {
+		p.buf.WriteString(" SET NOT NULL")
+	}
+
+	p.buf.WriteString(", ")
+	if err := writeAction(); err != nil {
+		return err
+	}
+
+	if c.Default == nil {
+		p.buf.WriteString(" DROP DEFAULT")
+		return nil
+	}
+
+	p.buf.WriteString(" SET DEFAULT ")
+	return p.writeDefault(c)
+}
+
 func (p *writer) writeAddColumnQuery(q *AddColumnQuery) error {
 	p.buf.WriteString("ALTER TABLE ")
 
@@ -222,22 +420,34 @@ func (p *writer) writeModifyColumnQuery(q *ModifyColumnQuery) error {
 		return err
 	}
 
-	p.buf.WriteString(" MODIFY ")
-
 	p.currentQuery = q
 
+	if !p.Dialect.Supports(ChangeColumn) {
+		p.buf.WriteString(" ")
+		return p.writeAlterColumn(q.Column)
+	}
+
+	p.buf.WriteString(" MODIFY ")
+
 	return p.writeCreateColumn(q.Column)
 }
 
 func (p *writer) writeDelete(s *DeleteQuery) error {
 	p.currentQuery = s
 
-	if p.driver == "sqlite3" {
+	if s.With != nil {
+		if err := p.writeWith(s.With, CTEUpdate); err != nil {
+			return err
+		}
+		p.currentQuery = s
+	}
+
+	if !p.Dialect.Supports(UpdateJoin) {
 		if len(s.Table.Joins) > 0 {
-			return fmt.Errorf("Invalid operation: UPDATE JOIN not supported in sqlite3")
+			return fmt.Errorf("Invalid operation: UPDATE JOIN not supported in %s", p.driver)
 		}
 		if len(s.Table.Alias) > 0 {
-			return fmt.Errorf("Invalid operation: UPDATE with Alias not supported in sqlite3")
+			return fmt.Errorf("Invalid operation: UPDATE with Alias not supported in %s", p.driver)
 		}
 	}
 
@@ -276,6 +486,10 @@ func (p *writer) writeDelete(s *DeleteQuery) error {
 	}
 
 	if s.LimitPart != nil {
+		if !p.Dialect.Supports(UpdateLimit) {
+			return fmt.Errorf("Invalid operation: DELETE with LIMIT not supported in %s", p.driver)
+		}
+
 		err := p.writeLimit(s.LimitPart)
 		if err != nil {
 			return err
@@ -288,12 +502,19 @@ func (p *writer) writeDelete(s *DeleteQuery) error {
 func (p *writer) writeUpdate(s *UpdateQuery) error {
 	p.currentQuery = s
 
-	if p.driver == "sqlite3" {
+	if s.With != nil {
+		if err := p.writeWith(s.With, CTEUpdate); err != nil {
+			return err
+		}
+		p.currentQuery = s
+	}
+
+	if !p.Dialect.Supports(UpdateJoin) {
 		if len(s.Table.Joins) > 0 {
-			return fmt.Errorf("Invalid operation: UPDATE JOIN not supported in sqlite3")
+			return fmt.Errorf("Invalid operation: UPDATE JOIN not supported in %s", p.driver)
 		}
 		if len(s.Table.Alias) > 0 {
-			return fmt.Errorf("Invalid operation: UPDATE with Alias not supported in sqlite3")
+			return fmt.Errorf("Invalid operation: UPDATE with Alias not supported in %s", p.driver)
 		}
 	}
 
@@ -335,6 +556,10 @@ func (p *writer) writeUpdate(s *UpdateQuery) error {
 	}
 
 	if s.LimitPart != nil {
+		if !p.Dialect.Supports(UpdateLimit) {
+			return fmt.Errorf("Invalid operation: UPDATE with LIMIT not supported in %s", p.driver)
+		}
+
 		err := p.writeLimit(s.LimitPart)
 		if err != nil {
 			return err
@@ -381,20 +606,133 @@ func (p *writer) writeInsert(s *InsertQuery) error {
 		return p.writeSelect(s.Select)
 	}
 
-	p.buf.WriteString("VALUES (")
+	if len(s.Rows) == 0 {
+		return fmt.Errorf("Expected VALUES at %v", s.Pos)
+	}
+
+	p.buf.WriteString("VALUES ")
+
+	for i, row := range s.Rows {
+		if len(s.Columns) > 0 && len(row) != len(s.Columns) {
+			return fmt.Errorf("Expected %d values, got %d at %v", len(s.Columns), len(row), s.Pos)
+		}
+
+		if len(row) != len(s.Rows[0]) {
+			return fmt.Errorf("Expected %d values, got %d at %v", len(s.Rows[0]), len(row), s.Pos)
+		}
 
-	for i, v := range s.Values {
 		if i > 0 {
 			p.buf.WriteString(", ")
 		}
 
-		err := p.writeExpr(v)
-		if err != nil {
+		p.buf.WriteRune('(')
+
+		for j, v := range row {
+			if j > 0 {
+				p.buf.WriteString(", ")
+			}
+
+			err := p.writeExpr(v)
+			if err != nil {
+				return err
+			}
+		}
+
+		p.buf.WriteRune(')')
+	}
+
+	if s.OnConflict != nil {
+		if p.Format {
+			p.buf.WriteRune('\n')
+		} else {
+			p.buf.WriteRune(' ')
+		}
+
+		return p.writeOnConflict(s.OnConflict)
+	}
+
+	return nil
+}
+
+func (p *writer) writeOnConflict(s *OnConflict) error {
+	columns := make([]string, len(s.Columns))
+	for i, c := range s.Columns {
+		if err := p.validateIdentifier(c); err != nil {
 			return err
 		}
+		columns[i] = p.quoteIdentifier(c)
 	}
 
-	p.buf.WriteString(")")
+	if !s.DoNothing && len(s.Update) == 0 {
+		return fmt.Errorf("Expected the columns to update at %v", s.Pos)
+	}
+
+	var update []string
+
+	if !s.DoNothing {
+		buf := p.buf
+		p.upsert = true
+
+		for _, c := range s.Update {
+			p.buf = new(bytes.Buffer)
+			err := p.writeColumnValue(c)
+			update = append(update, p.buf.String())
+			if err != nil {
+				p.buf = buf
+				p.upsert = false
+				return err
+			}
+		}
+
+		p.buf = buf
+		p.upsert = false
+	}
+
+	sql, err := p.Dialect.OnConflict(columns, update, s.DoNothing)
+	if err != nil {
+		return fmt.Errorf("%v at %v", err, s.Pos)
+	}
+
+	p.buf.WriteString(sql)
+	return nil
+}
+
+func (p *writer) writeCastExpr(t *CastExpr) error {
+	if err := p.validateAlphanumeric(t.Size); err != nil {
+		return err
+	}
+
+	if err := p.validateAlphanumeric(t.Decimals); err != nil {
+		return err
+	}
+
+	typ, err := p.Dialect.CastType(&CreateColumn{Type: t.Type, Size: t.Size, Decimals: t.Decimals, Unsigned: t.Unsigned})
+	if err != nil {
+		return fmt.Errorf("%v at %v", err, t.Pos)
+	}
+
+	p.buf.WriteString("CAST(")
+
+	if err := p.writeExpr(t.Expr); err != nil {
+		return err
+	}
+
+	p.buf.WriteString(" AS ")
+	p.buf.WriteString(typ)
+	p.buf.WriteRune(')')
+	return nil
+}
+
+func (p *writer) writeExcludedExpr(t *ExcludedExpr) error {
+	if !p.upsert {
+		return fmt.Errorf("Invalid reference to an inserted value at %v", t.Pos)
+	}
+
+	if err := p.validateIdentifier(t.Name); err != nil {
+		return err
+	}
+
+	p.buf.WriteString(p.Dialect.Excluded(p.quoteIdentifier(t.Name)))
 	return nil
 }
 
@@ -506,132 +844,228 @@ func (p *writer) writeDropTable(s *DropTableQuery) error {
 	return nil
 }
 
-func (p *writer) writeShow(s *ShowQuery) error {
+func (p *writer) writeCreateIndex(s *CreateIndexQuery) error {
 	p.currentQuery = s
 
-	switch strings.ToLower(s.Type) {
-	case "databases":
-		return p.writeShowDatabases(s)
-	case "tables":
-		return p.writeShowTables(s)
-	case "columns":
-		return p.writeShowColumns(s)
-	case "index":
-		return p.writeShowIndex(s)
-	default:
-		return fmt.Errorf("Invalid identifier %s at %v", s.Type, s.Pos)
+	if s.IfNotExists && !p.Dialect.Supports(IndexIfExists) {
+		return fmt.Errorf("'IF NOT EXISTS' is not supported in CREATE INDEX at %v", s.Pos)
 	}
-}
 
-func (p *writer) writeShowDatabases(s *ShowQuery) error {
-	if p.Database != "" {
-		return fmt.Errorf("Invalid database in SHOW DATABASES at %v", s.Pos)
+	if len(s.Columns) == 0 {
+		return fmt.Errorf("Index without columns at %v", s.Pos)
 	}
 
-	switch p.driver {
-	case "sqlite3":
-		return fmt.Errorf("Sqlite doesn't support 'SHOW DATABASES' at %v", s.Pos)
-	default:
-		p.buf.WriteString("SHOW DATABASES")
+	p.buf.WriteString("CREATE ")
+
+	if s.Unique {
+		p.buf.WriteString("UNIQUE ")
 	}
-	return nil
-}
 
-func (p *writer) validateDatabase(name string) bool {
-	if p.Database != "" {
-		if name != "" && name != p.Database {
-			return false
-		}
+	p.buf.WriteString("INDEX ")
+
+	if s.IfNotExists {
+		p.buf.WriteString("IF NOT EXISTS ")
 	}
-	return true
-}
 
-func (p *writer) writeShowTables(s *ShowQuery) error {
-	if !p.validateDatabase(s.Database) {
-		return fmt.Errorf("Invalid database %s at %v", s.Database, s.Pos)
+	if err := p.writeIndexName(s.Database, s.Name, false); err != nil {
+		return err
 	}
 
-	database := s.Database
-	if database == "" {
-		database = p.Database
+	p.buf.WriteString(" ON ")
+
+	if err := p.writeTable(s.Database, s.Table, true); err != nil {
+		return err
 	}
 
-	switch p.driver {
-	case "sqlite3":
-		p.buf.WriteString(`SELECT name FROM sqlite_master WHERE type = "table"`)
-		if database != "" {
-			p.buf.WriteString(` AND name like "`)
-			if err := p.writeUnescapedAlphanumeric(database); err != nil {
-				return err
-			}
-			p.buf.WriteString(`%"`)
-		}
-	default:
-		p.buf.WriteString("SHOW TABLES")
-		if database != "" {
-			p.buf.WriteString(" FROM ")
-			if err := p.writeIdentifier(database); err != nil {
-				return err
-			}
+	p.buf.WriteString(" (")
+
+	for i, c := range s.Columns {
+		if i > 0 {
+			p.buf.WriteString(", ")
 		}
-	}
-	return nil
-}
 
-func (p *writer) writeShowColumns(q *ShowQuery) error {
-	switch p.driver {
-	case "sqlite3":
-		p.buf.WriteString("PRAGMA table_info(")
-		if err := p.writeTable(q.Database, q.Table, false); err != nil {
+		if err := p.writeIdentifier(c.Name); err != nil {
 			return err
 		}
-		p.buf.WriteString(")")
-	default:
-		p.buf.WriteString("SHOW COLUMNS FROM ")
-		if err := p.writeTable(q.Database, q.Table, false); err != nil {
-			return err
+
+		if c.Length == "" {
+			continue
 		}
-	}
-	return nil
-}
 
-func (p *writer) writeShowIndex(q *ShowQuery) error {
-	switch p.driver {
-	case "sqlite3":
-		p.buf.WriteString("PRAGMA index_list(")
-		if err := p.writeTable(q.Database, q.Table, false); err != nil {
-			return err
+		if !isDigits(c.Length) {
+			return fmt.Errorf("Invalid index length %s at %v", c.Length, s.Pos)
 		}
-		p.buf.WriteString(")")
-	default:
-		p.buf.WriteString("SHOW INDEX FROM ")
-		if err := p.writeTable(q.Database, q.Table, false); err != nil {
-			return err
+
+		if !p.Dialect.Supports(PrefixIndexes) {
+			// indexing the whole value is equivalent except that
+			// a unique index would be more restrictive.
+			if s.Unique {
+				return fmt.Errorf("Prefix indexes are not supported at %v", s.Pos)
+			}
+			continue
 		}
+
+		p.buf.WriteString("(" + c.Length + ")")
 	}
+
+	p.buf.WriteString(")")
 	return nil
 }
 
-func (p *writer) writeCreateDatabase(s *CreateDatabaseQuery) error {
+func (p *writer) writeDropIndex(s *DropIndexQuery) error {
 	p.currentQuery = s
 
-	if p.driver == "sqlite3" {
-		return fmt.Errorf("Not supported at %v", s.Pos)
+	if s.IfExists && !p.Dialect.Supports(IndexIfExists) {
+		return fmt.Errorf("'IF EXISTS' is not supported in DROP INDEX at %v", s.Pos)
 	}
 
-	p.buf.WriteString("CREATE DATABASE ")
+	tableIndexNames := p.Dialect.Supports(TableIndexNames)
 
-	if s.IfNotExists {
-		p.buf.WriteString("IF NOT EXISTS ")
+	if tableIndexNames && s.Table == "" {
+		return fmt.Errorf("DROP INDEX requires ON table at %v", s.Pos)
 	}
 
-	if err := p.writeIdentifier(s.Name); err != nil {
+	p.buf.WriteString("DROP INDEX ")
+
+	if s.IfExists {
+		p.buf.WriteString("IF EXISTS ")
+	}
+
+	if err := p.writeIndexName(s.Database, s.Name, true); err != nil {
 		return err
 	}
-	return nil
-}
 
-func (p *writer) writeCreateTable(s *CreateTableQuery) error {
+	if tableIndexNames {
+		p.buf.WriteString(" ON ")
+		if err := p.writeTable(s.Database, s.Table, true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeIndexName writes the name of an index. If the database doesn't
+// scope index names to a table they are prefixed as the tables so a
+// namespace can't drop the indexes of another. qualify is false in
+// CREATE INDEX because the index is created in the schema of the table.
+func (p *writer) writeIndexName(database, name string, qualify bool) error {
+	if p.Dialect.Supports(TableIndexNames) {
+		return p.writeIdentifier(name)
+	}
+
+	if !p.validateDatabase(database) {
+		return fmt.Errorf("Invalid database %s", database)
+	}
+
+	var err error
+	name, err = p.prefixTableName(name, true)
+	if err != nil {
+		return err
+	}
+
+	if database == "" {
+		database = p.Database
+	}
+
+	if database != "" {
+		if !p.Dialect.Supports(Databases) {
+			return p.writeIdentifier(database + "_" + name)
+		}
+
+		if qualify {
+			if err := p.writeIdentifier(database); err != nil {
+				return err
+			}
+			p.buf.WriteString(".")
+		}
+	}
+
+	return p.writeIdentifier(name)
+}
+
+func (p *writer) writeShow(s *ShowQuery) error {
+	p.currentQuery = s
+
+	typ := strings.ToLower(s.Type)
+
+	var database, table string
+
+	switch typ {
+	case "databases":
+		if p.Database != "" {
+			return fmt.Errorf("Invalid database in SHOW DATABASES at %v", s.Pos)
+		}
+	case "tables", "columns", "index":
+		if !p.validateDatabase(s.Database) {
+			return fmt.Errorf("Invalid database %s at %v", s.Database, s.Pos)
+		}
+
+		database = s.Database
+		if database == "" {
+			database = p.Database
+		}
+
+		if err := p.validateIdentifier(database); err != nil {
+			return err
+		}
+
+		if typ != "tables" {
+			var err error
+			table, err = p.prefixTableName(s.Table, false)
+			if err != nil {
+				return err
+			}
+			if err := p.validateIdentifier(table); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("Invalid identifier %s at %v", s.Type, s.Pos)
+	}
+
+	sql, err := p.Dialect.Show(typ, database, table, p.quoteIdentifier)
+	if err != nil {
+		return fmt.Errorf("%v at %v", err, s.Pos)
+	}
+
+	p.buf.WriteString(sql)
+	return nil
+}
+
+func (p *writer) validateDatabase(name string) bool {
+	if p.Database != "" {
+		if name != "" && name != p.Database {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *writer) writeCreateDatabase(s *CreateDatabaseQuery) error {
+	p.currentQuery = s
+
+	if !p.Dialect.Supports(CreateDatabase) {
+		return fmt.Errorf("Not supported at %v", s.Pos)
+	}
+
+	if s.IfNotExists && !p.Dialect.Supports(CreateDatabaseIfNotExists) {
+		return fmt.Errorf("'IF NOT EXISTS' is not supported in CREATE DATABASE at %v", s.Pos)
+	}
+
+	p.buf.WriteString("CREATE DATABASE ")
+
+	if s.IfNotExists {
+		p.buf.WriteString("IF NOT EXISTS ")
+	}
+
+	if err := p.writeIdentifier(s.Name); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *writer) writeCreateTable(s *CreateTableQuery) error {
 	p.currentQuery = s
 
 	p.buf.WriteString("CREATE TABLE ")
@@ -646,7 +1080,10 @@ func (p *writer) writeCreateTable(s *CreateTableQuery) error {
 
 	p.buf.WriteString(" (")
 
-	var key *CreateColumn
+	primary, auto, err := primaryKey(s)
+	if err != nil {
+		return err
+	}
 
 	for i, col := range s.Columns {
 		if i > 0 {
@@ -662,13 +1099,17 @@ func (p *writer) writeCreateTable(s *CreateTableQuery) error {
 		if err != nil {
 			return err
 		}
+	}
 
-		if key == nil && col.Key {
-			key = col
+	if _, ok := p.Dialect.AutoIncrement(); !ok && auto != nil {
+		// the attribute of the column already declares the primary key
+		if len(primary) != 1 {
+			return fmt.Errorf("The auto increment column %s must be the only one of the primary key", auto.Name)
 		}
+		primary = nil
 	}
 
-	if p.driver == "mysql" && key != nil {
+	if len(primary) > 0 {
 		p.buf.WriteString(", ")
 		if p.Format {
 			p.buf.WriteString("\n\t")
@@ -676,8 +1117,13 @@ func (p *writer) writeCreateTable(s *CreateTableQuery) error {
 
 		p.buf.WriteString("PRIMARY KEY(")
 
-		if err := p.writeIdentifier(key.Name); err != nil {
-			return err
+		for i, name := range primary {
+			if i > 0 {
+				p.buf.WriteString(", ")
+			}
+			if err := p.writeIdentifier(name); err != nil {
+				return err
+			}
 		}
 
 		p.buf.WriteString(")")
@@ -697,6 +1143,11 @@ func (p *writer) writeCreateTable(s *CreateTableQuery) error {
 			if err := p.writeFKConstraint(s, t); err != nil {
 				return err
 			}
+		case *Check:
+			p.currentQuery = s
+			if err := p.writeCheck(t); err != nil {
+				return err
+			}
 		default:
 			panic(fmt.Sprintf("not implemented %T", c))
 		}
@@ -708,16 +1159,53 @@ func (p *writer) writeCreateTable(s *CreateTableQuery) error {
 
 	p.buf.WriteString(")")
 
-	if p.driver == "mysql" {
-		p.buf.WriteString(" ENGINE=InnoDb")
-		p.buf.WriteString(" DEFAULT CHARACTER SET = utf8")
-		p.buf.WriteString(" DEFAULT COLLATE = utf8_general_ci")
-		return nil
+	if options := p.Dialect.TableOptions(); options != "" {
+		p.buf.WriteRune(' ')
+		p.buf.WriteString(options)
 	}
 
 	return nil
 }
 
+// primaryKey returns the columns of the primary key of a table and the
+// column that auto increments if there is one.
+func primaryKey(s *CreateTableQuery) ([]string, *CreateColumn, error) {
+	columns := s.PrimaryKey
+	var auto *CreateColumn
+
+	for _, c := range s.Columns {
+		if c.Key || c.PrimaryKey {
+			if len(columns) > 0 {
+				return nil, nil, fmt.Errorf("Multiple primary keys in %s", s.Name)
+			}
+			columns = []string{c.Name}
+		}
+
+		if c.Key || c.AutoIncrement {
+			if auto != nil {
+				return nil, nil, fmt.Errorf("Multiple auto increment columns in %s", s.Name)
+			}
+			auto = c
+		}
+	}
+
+	if auto == nil {
+		return columns, nil, nil
+	}
+
+	if !auto.Type.isInteger() {
+		return nil, nil, fmt.Errorf("The auto increment column %s must be an int", auto.Name)
+	}
+
+	for _, name := range columns {
+		if strings.EqualFold(name, auto.Name) {
+			return columns, auto, nil
+		}
+	}
+
+	return nil, nil, fmt.Errorf("The auto increment column %s must be in the primary key", auto.Name)
+}
+
 func (p *writer) writeFKConstraint(s *CreateTableQuery, c *ForeginKey) error {
 
 	p.buf.WriteString(", CONSTRAINT ")
@@ -779,59 +1267,75 @@ func (p *writer) writeConstraint(s *CreateTableQuery, c *Constraint) error {
 	return nil
 }
 
-func (p *writer) writeCreateColumn(c *CreateColumn) error {
-	switch p.driver {
-	case "sqlite3":
-		return p.writeCreateColumnSqlite3(c)
-	default:
-		return p.writeCreateColumnMySQL(c)
+func (p *writer) writeCheck(c *Check) error {
+	p.buf.WriteString(", ")
+
+	if c.Name != "" {
+		p.buf.WriteString("CONSTRAINT ")
+		if err := p.writeIdentifier(c.Name); err != nil {
+			return err
+		}
+		p.buf.WriteString(" ")
+	}
+
+	p.buf.WriteString("CHECK ")
+	return p.writeDDLExpr(c.Expr)
+}
+
+// writeDDLExpr writes the expression of a CHECK or a generated column
+// in parenthesis. Parameters are not allowed because the databases
+// don't accept them in a CREATE or ALTER.
+func (p *writer) writeDDLExpr(e Expr) error {
+	n := p.paramSymbolCount
+
+	p.buf.WriteString("(")
+
+	if err := p.writeExpr(e); err != nil {
+		return err
+	}
+
+	p.buf.WriteString(")")
+
+	if p.paramSymbolCount != n {
+		return fmt.Errorf("Parameters are not allowed in a column definition at %v", e.Position())
 	}
+
+	return nil
 }
 
-func (p *writer) writeCreateColumnMySQL(c *CreateColumn) error {
+func (p *writer) writeCreateColumn(c *CreateColumn) error {
 	if err := p.writeIdentifier(c.Name); err != nil {
 		return err
 	}
 
-	switch c.Type {
-	case Int:
-		p.buf.WriteString(" int")
-	case Decimal:
-		p.buf.WriteString(" decimal")
-	case Char:
-		p.buf.WriteString(" char")
-	case Varchar:
-		p.buf.WriteString(" varchar")
-	case Text:
-		p.buf.WriteString(" text")
-	case MediumText:
-		p.buf.WriteString(" mediumtext")
-	case Bool:
-		p.buf.WriteString(" bool")
-	case Blob:
-		p.buf.WriteString(" blob")
-	case DatTime:
-		p.buf.WriteString(" datetime")
+	typ, err := p.columnType(c)
+	if err != nil {
+		return err
 	}
 
-	if c.Size != "" {
-		p.buf.WriteString("(")
-		if err := p.writeUnescapedAlphanumeric(c.Size); err != nil {
-			return err
-		}
+	if typ != "" {
+		p.buf.WriteRune(' ')
+		p.buf.WriteString(typ)
+	}
 
-		if c.Decimals != "" {
-			p.buf.WriteString(",")
-			if err := p.writeUnescapedAlphanumeric(c.Decimals); err != nil {
-				return err
-			}
+	if _, ok := p.currentQuery.(*CreateTableQuery); !ok && c.PrimaryKey {
+		return fmt.Errorf("PRIMARY KEY is only supported in CREATE TABLE")
+	}
 
+	if c.Key || c.AutoIncrement {
+		if attr, _ := p.Dialect.AutoIncrement(); attr != "" {
+			p.buf.WriteRune(' ')
+			p.buf.WriteString(attr)
 		}
-		p.buf.WriteString(")")
 	}
 
-	if c.Key {
-		p.buf.WriteString(" AUTO_INCREMENT")
+	if c.Generated != nil {
+		if c.Key || c.AutoIncrement || c.Default != nil {
+			return fmt.Errorf("The generated column %s can't have a default value", c.Name)
+		}
+		if err := p.writeGenerated(c); err != nil {
+			return err
+		}
 	}
 
 	if !c.Nullable {
@@ -839,140 +1343,231 @@ func (p *writer) writeCreateColumnMySQL(c *CreateColumn) error {
 	}
 	p.buf.WriteString(" NULL")
 
-	if c.Default != "" {
+	if c.Default != nil {
 		p.buf.WriteString(" DEFAULT ")
-		if err := p.writeQuotedAlphanumeric(c.Default); err != nil {
+		if err := p.writeDefault(c); err != nil {
 			return err
 		}
 	}
 
-	return nil
-}
-
-func (p *writer) writeCreateColumnSqlite3(c *CreateColumn) error {
-	if err := p.writeIdentifier(c.Name); err != nil {
-		return err
+	if c.OnUpdate != nil {
+		if err := p.writeOnUpdate(c); err != nil {
+			return err
+		}
 	}
 
-	switch c.Type {
-	case Int:
-		p.buf.WriteString(" INTEGER")
-	case Decimal:
-		p.buf.WriteString(" REAL")
-	case Char, Varchar:
-		p.buf.WriteString(" VARCHAR")
-	case Text, MediumText:
-		p.buf.WriteString(" TEXT")
-	case Bool:
-		p.buf.WriteString(" BOOLEAN")
-	case DatTime:
-		p.buf.WriteString(" DATETIME")
+	// comments are only metadata so they are omitted if not supported.
+	if c.Comment != "" && p.Dialect.Supports(ColumnComments) {
+		p.buf.WriteString(" COMMENT ")
+		p.buf.WriteString(p.Dialect.StringLiteral(c.Comment))
 	}
 
-	if c.Size != "" {
-		p.buf.WriteString("(")
-		if err := p.writeUnescapedAlphanumeric(c.Size); err != nil {
+	if c.Check != nil {
+		p.buf.WriteString(" CHECK ")
+		if err := p.writeDDLExpr(c.Check); err != nil {
 			return err
 		}
+	}
 
-		if c.Decimals != "" {
-			p.buf.WriteString(",")
-			if err := p.writeUnescapedAlphanumeric(c.Decimals); err != nil {
-				return err
-			}
+	if c.Type == Enum && !p.Dialect.Supports(Enums) {
+		if err := p.writeEnumCheck(c); err != nil {
+			return err
 		}
-		p.buf.WriteString(")")
 	}
 
-	if c.Key {
-		p.buf.WriteString(" PRIMARY KEY")
+	if collation := p.Dialect.ColumnCollation(c); collation != "" {
+		p.buf.WriteString(" COLLATE ")
+		p.buf.WriteString(collation)
 	}
 
-	if !c.Nullable {
-		p.buf.WriteString(" NOT")
+	return nil
+}
+
+// columnType validates the definition of a column and returns its type.
+func (p *writer) columnType(c *CreateColumn) (string, error) {
+	if err := p.validateAlphanumeric(c.Size); err != nil {
+		return "", err
 	}
-	p.buf.WriteString(" NULL")
 
-	if c.Default != "" {
-		p.buf.WriteString(" DEFAULT ")
-		if err := p.writeQuotedAlphanumeric(c.Default); err != nil {
-			return err
-		}
+	if err := p.validateAlphanumeric(c.Decimals); err != nil {
+		return "", err
 	}
 
-	switch c.Type {
-	case Char, Varchar, Text, MediumText:
-		p.buf.WriteString(" COLLATE NOCASE")
+	if c.Unsigned && !c.Type.isNumeric() {
+		return "", fmt.Errorf("UNSIGNED is only valid in numeric columns: %s", c.Name)
 	}
 
-	return nil
+	if (c.Type == Enum) != (len(c.Values) > 0) {
+		return "", fmt.Errorf("Invalid ENUM values in column %s", c.Name)
+	}
+
+	return p.Dialect.ColumnType(c)
 }
 
-func (p *writer) writeSelect(s *SelectQuery) error {
-	p.currentQuery = s
+// writeDefault writes the default value of a column. Constants and the
+// current date keywords are written as they are and any other expression
+// in parenthesis that is how all databases accept them.
+func (p *writer) writeDefault(c *CreateColumn) error {
+	switch t := c.Default.(type) {
+	case *ConstantExpr:
+		switch t.Kind {
+		case TRUE:
+			// the keyword is valid in all databases. Not 1 as a sqlite literal.
+			p.buf.WriteString("true")
+			return nil
+		case FALSE:
+			p.buf.WriteString("false")
+			return nil
+		case DEFAULT:
+			return fmt.Errorf("Invalid default value in column %s", c.Name)
+		}
+		return p.writeConstantExpr(t)
 
-	p.buf.WriteString("SELECT ")
+	case *ColumnNameExpr:
+		if t.Table == "" && isCurrentTime(t.Name) {
+			p.buf.WriteString(strings.ToUpper(t.Name))
+			return nil
+		}
+		return fmt.Errorf("Invalid default value %s in column %s", t.Name, c.Name)
+	}
 
-	if s.Distinct {
-		p.buf.WriteString("DISTINCT ")
+	if !p.Dialect.Supports(ExpressionDefaults) {
+		return fmt.Errorf("Expression defaults are not supported: %s", c.Name)
 	}
 
-	for i, col := range s.Columns {
-		if i > 0 {
-			p.buf.WriteString(", ")
-		}
-		if p.Format {
-			p.buf.WriteString("\n   ")
-		}
-		err := p.writeExpr(col)
-		if err != nil {
-			return err
-		}
+	if t, ok := c.Default.(*ParenExpr); ok {
+		return p.writeDDLExpr(t.X)
 	}
 
-	if s.From != nil {
-		if p.Format {
-			p.buf.WriteRune('\n')
-		} else {
-			p.buf.WriteRune(' ')
-		}
+	return p.writeDDLExpr(c.Default)
+}
 
-		p.buf.WriteString("FROM ")
+// writeOnUpdate writes ON UPDATE CURRENT_TIMESTAMP, the only value that
+// MySQL accepts, with the precision of the fractional seconds if it has one.
+func (p *writer) writeOnUpdate(c *CreateColumn) error {
+	if !p.Dialect.Supports(OnUpdateTimestamp) {
+		return fmt.Errorf("ON UPDATE is not supported: %s", c.Name)
+	}
 
-		for i, from := range s.From {
-			if i > 0 {
-				p.buf.WriteString(", ")
-			}
+	var name, precision string
 
-			if err := p.writeFrom(from); err != nil {
-				return err
+	switch t := c.OnUpdate.(type) {
+	case *ColumnNameExpr:
+		if t.Table == "" {
+			name = t.Name
+		}
+	case *CallExpr:
+		name = t.Name
+		switch len(t.Args) {
+		case 0:
+		case 1:
+			k, ok := t.Args[0].(*ConstantExpr)
+			if !ok || k.Kind != INT || !isDigits(k.Value) {
+				return fmt.Errorf("Invalid ON UPDATE precision in column %s", c.Name)
 			}
+			precision = k.Value
+		default:
+			return fmt.Errorf("Invalid ON UPDATE precision in column %s", c.Name)
 		}
 	}
 
-	if s.WherePart != nil {
-		if p.Format {
-			p.buf.WriteRune('\n')
-		} else {
-			p.buf.WriteRune(' ')
-		}
+	switch strings.ToUpper(name) {
+	case "CURRENT_TIMESTAMP", "LOCALTIMESTAMP", "NOW":
+	default:
+		return fmt.Errorf("ON UPDATE only supports CURRENT_TIMESTAMP in column %s", c.Name)
+	}
 
-		p.buf.WriteString("WHERE ")
+	p.buf.WriteString(" ON UPDATE CURRENT_TIMESTAMP")
 
-		err := p.writeExpr(s.WherePart.Expr)
-		if err != nil {
-			return err
-		}
+	if precision != "" {
+		p.buf.WriteString("(" + precision + ")")
 	}
 
-	if len(s.GroupByPart) > 0 {
-		if p.Format {
-			p.buf.WriteRune('\n')
-		} else {
-			p.buf.WriteRune(' ')
-		}
+	return nil
+}
 
-		p.buf.WriteString("GROUP BY ")
+// isCurrentTime returns true for the keywords that are the current
+// date or time.
+func isCurrentTime(name string) bool {
+	switch strings.ToUpper(name) {
+	case "CURRENT_TIMESTAMP", "CURRENT_DATE", "CURRENT_TIME", "LOCALTIMESTAMP", "LOCALTIME":
+		return true
+	}
+	return false
+}
+
+func (p *writer) writeGenerated(c *CreateColumn) error {
+	if !p.Dialect.Supports(GeneratedColumns) {
+		return fmt.Errorf("Generated columns are not supported: %s", c.Name)
+	}
+
+	if !c.Stored && !p.Dialect.Supports(VirtualColumns) {
+		return fmt.Errorf("VIRTUAL generated columns are not supported: %s", c.Name)
+	}
+
+	p.buf.WriteString(" GENERATED ALWAYS AS ")
+
+	if err := p.writeDDLExpr(c.Generated); err != nil {
+		return err
+	}
+
+	if c.Stored {
+		p.buf.WriteString(" STORED")
+	} else {
+		p.buf.WriteString(" VIRTUAL")
+	}
+
+	return nil
+}
+
+// writeEnumCheck emulates an ENUM restricting the values of a text column.
+func (p *writer) writeEnumCheck(c *CreateColumn) error {
+	p.buf.WriteString(" CHECK (")
+
+	if err := p.writeIdentifier(c.Name); err != nil {
+		return err
+	}
+
+	p.buf.WriteString(" IN (")
+
+	for i, v := range c.Values {
+		if i > 0 {
+			p.buf.WriteString(", ")
+		}
+		p.buf.WriteString(p.Dialect.StringLiteral(v))
+	}
+
+	p.buf.WriteString("))")
+	return nil
+}
+
+func (p *writer) writeSelect(s *SelectQuery) error {
+	p.currentQuery = s
+
+	localTables := len(p.localTables)
+
+	if s.With != nil {
+		if err := p.writeWith(s.With, CTE); err != nil {
+			return err
+		}
+		p.currentQuery = s
+	}
+
+	// where the compound starts in case that it has to be emulated
+	start := p.buf.Len()
+
+	if err := p.writeSelectWhere(s); err != nil {
+		return err
+	}
+
+	if len(s.GroupByPart) > 0 {
+		if p.Format {
+			p.buf.WriteRune('\n')
+		} else {
+			p.buf.WriteRune(' ')
+		}
+
+		p.buf.WriteString("GROUP BY ")
 
 		for i, group := range s.GroupByPart {
 			if i > 0 {
@@ -1001,6 +1596,25 @@ func (p *writer) writeSelect(s *SelectQuery) error {
 		}
 	}
 
+	if len(s.Windows) > 0 {
+		if p.Format {
+			p.buf.WriteRune('\n')
+		} else {
+			p.buf.WriteRune(' ')
+		}
+
+		if err := p.writeWindows(s.Windows); err != nil {
+			return err
+		}
+	}
+
+	if len(s.UnionPart) > 0 {
+		if err := p.writeSetOperations(s, start); err != nil {
+			return err
+		}
+		p.currentQuery = s
+	}
+
 	if len(s.OrderByPart) > 0 {
 		if p.Format {
 			p.buf.WriteRune('\n')
@@ -1020,26 +1634,289 @@ func (p *writer) writeSelect(s *SelectQuery) error {
 		}
 	}
 
-	for _, u := range s.UnionPart {
+	if s.ForUpdate {
+		p.buf.WriteString(" FOR UPDATE")
+	}
+
+	// the common table expressions are only visible in this query.
+	p.localTables = p.localTables[:localTables]
+
+	return nil
+}
+
+// writeSelectWhere writes a select until the WHERE part included.
+func (p *writer) writeSelectWhere(s *SelectQuery) error {
+	allColumns, err := p.allColumns(s)
+	if err != nil {
+		return err
+	}
+
+	if from, joins := firstJoins(s); len(joins) > 0 && joins[0].Type == FULL && !p.Dialect.Supports(FullJoin) {
+		return p.emulateFullJoin(s, from, joins, allColumns)
+	}
+
+	p.buf.WriteString("SELECT ")
+
+	if s.Distinct {
+		p.buf.WriteString("DISTINCT ")
+	}
+
+	if err := p.writeSelectColumns(s.Columns, allColumns); err != nil {
+		return err
+	}
+
+	if s.From != nil {
 		if p.Format {
 			p.buf.WriteRune('\n')
 		} else {
 			p.buf.WriteRune(' ')
 		}
-		p.buf.WriteString("UNION ")
-		err := p.writeSelect(u)
+
+		p.buf.WriteString("FROM ")
+
+		for i, from := range s.From {
+			if i > 0 {
+				p.buf.WriteString(", ")
+			}
+
+			if err := p.writeFrom(from); err != nil {
+				return err
+			}
+		}
+	}
+
+	if s.WherePart != nil {
+		if p.Format {
+			p.buf.WriteRune('\n')
+		} else {
+			p.buf.WriteRune(' ')
+		}
+
+		p.buf.WriteString("WHERE ")
+
+		err := p.writeExpr(s.WherePart.Expr)
 		if err != nil {
 			return err
 		}
 	}
 
-	if s.ForUpdate {
-		p.buf.WriteString(" FOR UPDATE")
+	return nil
+}
+
+// writeSelectColumns writes the columns of a select. If allColumns is
+// not nil * is written as the columns of each table.
+func (p *writer) writeSelectColumns(columns []Expr, allColumns []string) error {
+	for i, col := range columns {
+		if i > 0 {
+			p.buf.WriteString(", ")
+		}
+		if p.Format {
+			p.buf.WriteString("\n   ")
+		}
+		if t, ok := col.(*AllColumnsExpr); ok && t.Table == "" && allColumns != nil {
+			p.buf.WriteString(strings.Join(allColumns, ", "))
+			continue
+		}
+		err := p.writeExpr(col)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSetOperations writes the UNION, INTERSECT and EXCEPT of a select.
+// start is the position in the buffer where the first select begins.
+func (p *writer) writeSetOperations(s *SelectQuery, start int) error {
+	for i, u := range s.UnionPart {
+		switch u.Type {
+		case UNION:
+		case INTERSECT, EXCEPT:
+			if !p.Dialect.Supports(IntersectExcept) {
+				if err := p.emulateSetOperation(s, i, start); err != nil {
+					return err
+				}
+				continue
+			}
+		default:
+			return fmt.Errorf("Invalid set operation %v at %v", u.Type, u.Pos)
+		}
+
+		if p.Format {
+			p.buf.WriteRune('\n')
+		} else {
+			p.buf.WriteRune(' ')
+		}
+
+		p.buf.WriteString(u.Type.String())
+
+		if u.All {
+			p.buf.WriteString(" ALL")
+		}
+
+		p.buf.WriteRune(' ')
+
+		if err := p.writeSelect(u.Query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// emulateSetOperation writes INTERSECT or EXCEPT with a UNION ALL of the
+// previous selects and the next one tagging the rows of each side:
+//
+//	SELECT a, b FROM (SELECT *, 1 AS goql_set FROM (...) AS goql_a
+//	UNION ALL SELECT *, 2 FROM (...) AS goql_b) AS goql_t
+//	GROUP BY a, b HAVING COUNT(DISTINCT goql_set) = 2
+//
+// The result is grouped by the columns of the first select so all of them
+// must have a name.
+func (p *writer) emulateSetOperation(s *SelectQuery, i, start int) error {
+	u := s.UnionPart[i]
+
+	if u.All {
+		return fmt.Errorf("%v ALL is not supported in %s at %v", u.Type, p.driver, u.Pos)
+	}
+
+	// the emulation is applied from left to right so it is only valid
+	// if there is nothing before an INTERSECT that has less precedence.
+	if u.Type == INTERSECT {
+		for _, prev := range s.UnionPart[:i] {
+			if prev.Type != INTERSECT {
+				return fmt.Errorf("INTERSECT after %v is not supported in %s at %v", prev.Type, p.driver, u.Pos)
+			}
+		}
+	}
+
+	var names []string
+	for _, c := range s.Columns {
+		var name string
+		switch t := c.(type) {
+		case *ColumnNameExpr:
+			name = t.Alias
+			if name == "" {
+				name = t.Name
+			}
+		case *SelectColumnExpr:
+			name = t.Alias
+		}
+
+		if name == "" {
+			return fmt.Errorf("%v is only supported in %s with named columns at %v", u.Type, p.driver, u.Pos)
+		}
+
+		if err := p.validateIdentifier(name); err != nil {
+			return err
+		}
+
+		names = append(names, p.quoteIdentifier(name))
+	}
+
+	columns := strings.Join(names, ", ")
+
+	prev := string(p.buf.Bytes()[start:])
+	p.buf.Truncate(start)
+
+	p.buf.WriteString("SELECT ")
+	p.buf.WriteString(columns)
+	p.buf.WriteString(" FROM (SELECT *, 1 AS goql_set FROM (")
+	p.buf.WriteString(prev)
+	p.buf.WriteString(") AS goql_a UNION ALL SELECT *, 2 FROM (")
+
+	if err := p.writeSelect(u.Query); err != nil {
+		return err
+	}
+
+	p.buf.WriteString(") AS goql_b) AS goql_t GROUP BY ")
+	p.buf.WriteString(columns)
+
+	if u.Type == INTERSECT {
+		p.buf.WriteString(" HAVING COUNT(DISTINCT goql_set) = 2")
+	} else {
+		p.buf.WriteString(" HAVING MAX(goql_set) = 1")
+	}
+
+	return nil
+}
+
+// writeWith writes the common table expressions. Their names are local
+// names and are never prefixed with the database or the namespace.
+func (p *writer) writeWith(w *WithClause, f Feature) error {
+	if !p.Dialect.Supports(f) {
+		return fmt.Errorf("WITH is not supported in this query in %s at %v", p.driver, w.Pos)
+	}
+
+	p.buf.WriteString("WITH ")
+
+	if w.Recursive {
+		p.buf.WriteString("RECURSIVE ")
+	}
+
+	for i, t := range w.Tables {
+		if i > 0 {
+			p.buf.WriteString(", ")
+		}
+
+		// a recursive query references itself but in a simple one
+		// the name is the table that is being replaced.
+		if w.Recursive {
+			p.localTables = append(p.localTables, t.Name)
+		}
+
+		if err := p.writeIdentifier(t.Name); err != nil {
+			return err
+		}
+
+		if len(t.Columns) > 0 {
+			p.buf.WriteString(" (")
+			for j, c := range t.Columns {
+				if j > 0 {
+					p.buf.WriteString(", ")
+				}
+				if err := p.writeIdentifier(c); err != nil {
+					return err
+				}
+			}
+			p.buf.WriteRune(')')
+		}
+
+		p.buf.WriteString(" AS (")
+
+		if t.Query == nil {
+			return fmt.Errorf("Expected a subquery at %v", t.Pos)
+		}
+
+		if err := p.writeSelect(t.Query); err != nil {
+			return err
+		}
+
+		p.buf.WriteRune(')')
+
+		if !w.Recursive {
+			p.localTables = append(p.localTables, t.Name)
+		}
+	}
+
+	if p.Format {
+		p.buf.WriteRune('\n')
+	} else {
+		p.buf.WriteRune(' ')
 	}
 
 	return nil
 }
 
+func (p *writer) isLocalTable(name string) bool {
+	for _, t := range p.localTables {
+		if strings.EqualFold(t, name) {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *writer) writeLimit(s *Limit) error {
 	if p.Format {
 		p.buf.WriteRune('\n')
@@ -1047,23 +1924,53 @@ func (p *writer) writeLimit(s *Limit) error {
 		p.buf.WriteRune(' ')
 	}
 
-	p.buf.WriteString("LIMIT ")
+	var offset string
 
 	if s.Offset != nil {
-		p.writeExpr(s.Offset)
-		p.buf.WriteString(", ")
+		var err error
+		offset, err = p.exprString(s.Offset)
+		if err != nil {
+			return err
+		}
+	}
+
+	rowCount, err := p.exprString(s.RowCount)
+	if err != nil {
+		return err
 	}
 
-	p.writeExpr(s.RowCount)
+	p.buf.WriteString(p.Dialect.Limit(offset, rowCount))
 	return nil
 }
 
+// exprString returns an expression written as a string
+// instead of writing it to the query.
+func (p *writer) exprString(e Expr) (string, error) {
+	buf := p.buf
+	p.buf = new(bytes.Buffer)
+	err := p.writeExpr(e)
+	s := p.buf.String()
+	p.buf = buf
+	return s, err
+}
+
 func (p *writer) writeFrom(s SqlFrom) error {
 	switch t := s.(type) {
 	case *Table:
+		if len(t.Joins) > 0 && t.Joins[0].Type == RIGHT && !p.Dialect.Supports(RightJoin) {
+			return p.emulateRightJoin(t, t.Joins)
+		}
 		return p.writeFromTable(t, false)
 	case *ParenExpr:
 		return p.writeParenExpr(t)
+	case *DerivedTable:
+		if len(t.Joins) > 0 && t.Joins[0].Type == RIGHT && !p.Dialect.Supports(RightJoin) {
+			return p.emulateRightJoin(t, t.Joins)
+		}
+		if err := p.writeDerivedTable(t); err != nil {
+			return err
+		}
+		return p.writeJoins(t.Joins)
 	case *FromAsExpr:
 		if err := p.writeParenExpr(t.From); err != nil {
 			return err
@@ -1075,6 +1982,35 @@ func (p *writer) writeFrom(s SqlFrom) error {
 	}
 }
 
+// writeDerivedTable writes a subquery in FROM or JOIN. The tables inside
+// are prefixed as in any other select but the alias is a local name.
+func (p *writer) writeDerivedTable(t *DerivedTable) error {
+	if t.Query == nil {
+		return fmt.Errorf("Expected a subquery at %v", t.Pos)
+	}
+
+	currentQuery := p.currentQuery
+
+	p.buf.WriteRune('(')
+
+	if err := p.writeSelect(t.Query); err != nil {
+		return err
+	}
+
+	p.buf.WriteRune(')')
+
+	p.currentQuery = currentQuery
+
+	if t.Alias != "" {
+		p.buf.WriteString(" AS ")
+		if err := p.writeIdentifier(t.Alias); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (p *writer) prefixTableName(table string, isWrite bool) (string, error) {
 	if p.IgnoreNamespaces {
 		return table, nil
@@ -1105,6 +2041,8 @@ func (p *writer) prefixTableName(table string, isWrite bool) (string, error) {
 		*ModifyColumnQuery,
 		*AlterDropQuery,
 		*DropTableQuery,
+		*CreateIndexQuery,
+		*DropIndexQuery,
 		*AddColumnQuery,
 		*AddFKQuery,
 		*AddConstraintQuery:
@@ -1164,195 +2102,559 @@ func ValidateNamespace(s string) error {
 	return nil
 }
 
-func validateSeparator(s string) error {
-	for _, c := range s {
-		switch c {
-		case ';', ' ', ',', '-', '_', '|':
-			continue
+func validateSeparator(s string) error {
+	for _, c := range s {
+		switch c {
+		case ';', ' ', ',', '-', '_', '|':
+			continue
+		}
+
+		return fmt.Errorf("Invalid identifier %s", s)
+	}
+	return nil
+}
+
+// namespaces have de format a[.b][.c][.d]...
+func (p *writer) ValidateNamespace(s string) error {
+	if p.IgnoreNamespaces {
+		return nil
+	}
+
+	for i, c := range s {
+		if i > 0 && c == ':' {
+			continue
+		}
+		if !isIdent(byte(c), i) {
+			return fmt.Errorf("Invalid identifier %s", s)
+		}
+	}
+	return nil
+}
+
+// isWrite indicates if it is a write operation protected by namespaces
+// (allowed to read from another namespace but not to write)
+func (p *writer) writeTable(database, table string, isWrite bool) error {
+	if !p.validateDatabase(database) {
+		return fmt.Errorf("Invalid database %s", database)
+	}
+
+	// common table expressions are not prefixed with the database or the namespace.
+	// The target of a write is always a table even if a CTE has the same name.
+	if !isWrite && database == "" && p.isLocalTable(table) {
+		return p.writeIdentifier(table)
+	}
+
+	var err error
+	table, err = p.prefixTableName(table, isWrite)
+	if err != nil {
+		return err
+	}
+
+	if database == "" {
+		database = p.Database
+	}
+
+	if database != "" {
+		if !p.Dialect.Supports(Databases) {
+			// use table prefix to simulate databases.
+			// Write as one identifier to avoid writing: `dbfoo`_`table`
+			return p.writeIdentifier(database + "_" + table)
+		}
+
+		if err := p.writeIdentifier(database); err != nil {
+			return err
+		}
+		p.buf.WriteString(".")
+	}
+
+	if err := p.writeIdentifier(table); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *writer) writeFromTable(t *Table, isWrite bool) error {
+	if err := p.writeTableAlias(t, isWrite); err != nil {
+		return err
+	}
+
+	return p.writeJoins(t.Joins)
+}
+
+func (p *writer) writeTableAlias(t *Table, isWrite bool) error {
+	if err := p.writeTable(t.Database, t.Name, isWrite); err != nil {
+		return err
+	}
+
+	if t.Alias != "" {
+		p.buf.WriteString(" AS ")
+		if err := p.writeIdentifier(t.Alias); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *writer) writeIdentifier(s string) error {
+	if err := p.validateIdentifier(s); err != nil {
+		return err
+	}
+
+	p.buf.WriteString(p.quoteIdentifier(s))
+	return nil
+}
+
+// validateIdentifier returns an error if s is not a valid identifier.
+func (p *writer) validateIdentifier(s string) error {
+	for i, c := range s {
+		if c == ':' && i > 0 && p.IgnoreNamespaces {
+			continue
+		}
+		if !isIdent(byte(c), i) {
+			return fmt.Errorf("Invalid identifier %s", s)
+		}
+	}
+	return nil
+}
+
+// quoteIdentifier quotes a valid identifier if EscapeIdents is set.
+func (p *writer) quoteIdentifier(s string) string {
+	if p.EscapeIdents {
+		return p.Dialect.QuoteIdent(s)
+	}
+	return s
+}
+
+func (p *writer) writeUnescapedAlphanumeric(s string) error {
+	if err := p.validateAlphanumeric(s); err != nil {
+		return err
+	}
+
+	p.buf.WriteString(s)
+	return nil
+}
+
+func (p *writer) validateAlphanumeric(s string) error {
+	for _, c := range s {
+		if !isIdent(byte(c), 1) {
+			return fmt.Errorf("Invalid identifier %s", s)
+		}
+	}
+	return nil
+}
+
+func (p *writer) writeJoins(joins []*Join) error {
+	for _, j := range joins {
+		if err := p.writeJoin(j); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *writer) writeJoin(join *Join) error {
+	switch join.Type {
+	case RIGHT:
+		if !p.Dialect.Supports(RightJoin) {
+			return fmt.Errorf("RIGHT JOIN is only supported as the first join in %s at %v", p.driver, join.Pos)
+		}
+	case FULL:
+		if !p.Dialect.Supports(FullJoin) {
+			return fmt.Errorf("FULL JOIN is only supported as the first join in %s at %v", p.driver, join.Pos)
+		}
+	}
+
+	if p.Format {
+		p.buf.WriteRune('\n')
+	} else {
+		p.buf.WriteRune(' ')
+	}
+
+	if err := p.writeJoinType(join, join.Type); err != nil {
+		return err
+	}
+
+	if err := p.writeJoinTarget(join); err != nil {
+		return err
+	}
+
+	return p.writeJoinCondition(join)
+}
+
+// writeJoinType writes the join keywords. typ can be different from
+// the type of the join when it is emulated.
+func (p *writer) writeJoinType(join *Join, typ Type) error {
+	if join.Natural {
+		switch join.Type {
+		case LEFT, RIGHT, INNER, JOIN:
+		default:
+			return fmt.Errorf("Invalid NATURAL join type: %v", join.Type)
+		}
+
+		if join.On != nil || len(join.Using) > 0 {
+			return fmt.Errorf("A NATURAL join can't have a condition at %v", join.Pos)
+		}
+
+		p.buf.WriteString("NATURAL ")
+	}
+
+	switch typ {
+	case LEFT:
+		p.buf.WriteString("LEFT JOIN ")
+	case RIGHT:
+		p.buf.WriteString("RIGHT JOIN ")
+	case FULL:
+		p.buf.WriteString("FULL JOIN ")
+	case INNER:
+		p.buf.WriteString("INNER JOIN ")
+	case OUTER:
+		p.buf.WriteString("OUTER JOIN ")
+	case CROSS:
+		p.buf.WriteString("CROSS JOIN ")
+	case JOIN:
+		p.buf.WriteString("JOIN ")
+	default:
+		return fmt.Errorf("Invalid join type: %v", typ)
+	}
+
+	return nil
+}
+
+// writeJoinTarget writes the joined table or subquery with its alias.
+func (p *writer) writeJoinTarget(join *Join) error {
+	if join.Subquery != nil {
+		if err := p.writeDerivedTable(join.Subquery); err != nil {
+			return err
+		}
+	} else if err := p.writeTable(join.Database, join.Table, false); err != nil {
+		return err
+	}
+
+	if join.Alias != "" {
+		p.buf.WriteString(" AS ")
+		if err := p.writeIdentifier(join.Alias); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *writer) writeJoinCondition(join *Join) error {
+	if join.On != nil && len(join.Using) > 0 {
+		return fmt.Errorf("A join can't have ON and USING at %v", join.Pos)
+	}
+
+	if join.On != nil {
+		p.buf.WriteString(" ON ")
+		if err := p.writeExpr(join.On); err != nil {
+			return err
+		}
+	}
+
+	if len(join.Using) > 0 {
+		p.buf.WriteString(" USING (")
+		for i, c := range join.Using {
+			if i > 0 {
+				p.buf.WriteString(", ")
+			}
+			if err := p.writeIdentifier(c); err != nil {
+				return err
+			}
+		}
+		p.buf.WriteRune(')')
+	}
+
+	return nil
+}
+
+// firstJoins returns the first table of a select and its joins.
+func firstJoins(s *SelectQuery) (SqlFrom, []*Join) {
+	if len(s.From) == 0 {
+		return nil, nil
+	}
+
+	switch t := s.From[0].(type) {
+	case *Table:
+		return t, t.Joins
+	case *DerivedTable:
+		return t, t.Joins
+	}
+
+	return nil, nil
+}
+
+// writeFromItem writes a table or subquery of FROM without its joins.
+func (p *writer) writeFromItem(from SqlFrom) error {
+	switch t := from.(type) {
+	case *Table:
+		return p.writeTableAlias(t, false)
+	case *DerivedTable:
+		return p.writeDerivedTable(t)
+	default:
+		return fmt.Errorf("Invalid from %T", t)
+	}
+}
+
+// allColumns returns how to write SELECT * if the select emulates a join
+// writing the tables in another order. Then * is written as the columns
+// of each table in the order of FROM: a.*, b.*. Otherwise it returns nil.
+func (p *writer) allColumns(s *SelectQuery) ([]string, error) {
+	var star *AllColumnsExpr
+	for _, col := range s.Columns {
+		if t, ok := col.(*AllColumnsExpr); ok && t.Table == "" {
+			star = t
+		}
+	}
+
+	if star == nil || !p.emulatesJoin(s) {
+		return nil, nil
+	}
+
+	var refs []string
+
+	add := func(alias, database, table string) error {
+		ref, err := p.capture(func() error {
+			if alias != "" {
+				return p.writeIdentifier(alias)
+			}
+			return p.writeTable(database, table, false)
+		})
+		if err != nil {
+			return err
+		}
+		refs = append(refs, ref.sql+".*")
+		return nil
+	}
+
+	for _, from := range s.From {
+		var joins []*Join
+
+		switch t := from.(type) {
+		case *Table:
+			if err := add(t.Alias, t.Database, t.Name); err != nil {
+				return nil, err
+			}
+			joins = t.Joins
+		case *DerivedTable:
+			if t.Alias == "" {
+				return nil, fmt.Errorf("SELECT * needs an alias for the subquery in %s at %v", p.driver, t.Pos)
+			}
+			if err := add(t.Alias, "", ""); err != nil {
+				return nil, err
+			}
+			joins = t.Joins
+		default:
+			return nil, fmt.Errorf("SELECT * is not supported with this join in %s at %v", p.driver, star.Pos)
 		}
 
-		return fmt.Errorf("Invalid identifier %s", s)
+		for _, j := range joins {
+			// the columns of USING are returned only once.
+			if j.Natural || len(j.Using) > 0 {
+				return nil, fmt.Errorf("SELECT * is not supported with NATURAL or USING in %s at %v", p.driver, j.Pos)
+			}
+
+			var err error
+			if j.Subquery != nil {
+				if j.Subquery.Alias == "" {
+					return nil, fmt.Errorf("SELECT * needs an alias for the subquery in %s at %v", p.driver, j.Pos)
+				}
+				err = add(j.Subquery.Alias, "", "")
+			} else {
+				err = add(j.Alias, j.Database, j.Table)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
-	return nil
+
+	return refs, nil
 }
 
-// namespaces have de format a[.b][.c][.d]...
-func (p *writer) ValidateNamespace(s string) error {
-	if p.IgnoreNamespaces {
-		return nil
-	}
+// emulatesJoin returns true if a RIGHT or FULL JOIN of the select is
+// emulated changing the order of the tables.
+func (p *writer) emulatesJoin(s *SelectQuery) bool {
+	for _, from := range s.From {
+		var joins []*Join
 
-	for i, c := range s {
-		if i > 0 && c == ':' {
+		switch t := from.(type) {
+		case *Table:
+			joins = t.Joins
+		case *DerivedTable:
+			joins = t.Joins
+		}
+
+		if len(joins) == 0 {
 			continue
 		}
-		if !isIdent(byte(c), i) {
-			return fmt.Errorf("Invalid identifier %s", s)
+
+		switch joins[0].Type {
+		case RIGHT:
+			if !p.Dialect.Supports(RightJoin) {
+				return true
+			}
+		case FULL:
+			if !p.Dialect.Supports(FullJoin) {
+				return true
+			}
 		}
 	}
-	return nil
+
+	return false
 }
 
-// isWrite indicates if it is a write operation protected by namespaces
-// (allowed to read from another namespace but not to write)
-func (p *writer) writeTable(database, table string, isWrite bool) error {
-	if !p.validateDatabase(database) {
-		return fmt.Errorf("Invalid database %s", database)
-	}
+// emulateRightJoin writes "a RIGHT JOIN b" as "b LEFT JOIN a". It is only
+// possible if it is the first join. SELECT * is written as the columns of
+// each table so they are returned in the same order.
+func (p *writer) emulateRightJoin(from SqlFrom, joins []*Join) error {
+	join := joins[0]
 
-	var err error
-	table, err = p.prefixTableName(table, isWrite)
+	a, err := p.capture(func() error { return p.writeFromItem(from) })
 	if err != nil {
 		return err
 	}
 
-	if database == "" {
-		database = p.Database
+	b, err := p.capture(func() error { return p.writeJoinTarget(join) })
+	if err != nil {
+		return err
 	}
 
-	if database != "" {
-		if p.driver == "sqlite3" {
-			// if it is sqlite3 use table prefix to simulate databases.
-			// Write as one identifier to avoid writing: `dbfoo`_`table`
-			return p.writeIdentifier(database + "_" + table)
-		}
+	p.repeatParams(a.from, b, a)
 
-		if err := p.writeIdentifier(database); err != nil {
-			return err
-		}
-		p.buf.WriteString(".")
-	}
+	p.buf.WriteString(b.sql)
+	p.buf.WriteRune(' ')
 
-	if err := p.writeIdentifier(table); err != nil {
+	if err := p.writeJoinType(join, LEFT); err != nil {
 		return err
 	}
-	return nil
-}
 
-func (p *writer) writeFromTable(t *Table, isWrite bool) error {
-	if err := p.writeTable(t.Database, t.Name, isWrite); err != nil {
-		return err
-	}
+	p.buf.WriteString(a.sql)
 
-	if t.Alias != "" {
-		p.buf.WriteString(" AS ")
-		if err := p.writeIdentifier(t.Alias); err != nil {
-			return err
-		}
+	if err := p.writeJoinCondition(join); err != nil {
+		return err
 	}
 
-	return p.writeJoins(t.Joins)
+	return p.writeJoins(joins[1:])
 }
 
-func (p *writer) writeIdentifier(s string) error {
-	// validate that is an identifier
-	for i, c := range s {
-		if c == ':' && i > 0 && p.IgnoreNamespaces {
-			continue
-		}
-		if !isIdent(byte(c), i) {
-			return fmt.Errorf("Invalid identifier %s", s)
-		}
-	}
+// emulateFullJoin writes "a FULL JOIN b ON x" as the rows of a LEFT JOIN
+// and the rows of b that don't match any row of a:
+//
+//	SELECT ... FROM a LEFT JOIN b ON x WHERE w
+//	UNION ALL
+//	SELECT ... FROM b LEFT JOIN a ON x WHERE NOT EXISTS (SELECT 1 FROM a WHERE x) AND (w)
+//
+// Each part is written once and repeated so the parameters are repeated too.
+func (p *writer) emulateFullJoin(s *SelectQuery, from SqlFrom, joins []*Join, allColumns []string) error {
+	join := joins[0]
 
-	if p.EscapeIdents {
-		p.buf.WriteRune('`')
+	if len(s.From) > 1 || s.Distinct || len(s.GroupByPart) > 0 || s.HavingPart != nil ||
+		len(s.Windows) > 0 || len(s.UnionPart) > 0 {
+		return fmt.Errorf("FULL JOIN is only supported in a simple select in %s at %v", p.driver, join.Pos)
 	}
 
-	p.buf.WriteString(s)
-
-	if p.EscapeIdents {
-		p.buf.WriteRune('`')
+	if join.On == nil || join.Natural {
+		return fmt.Errorf("FULL JOIN is only supported with ON in %s at %v", p.driver, join.Pos)
 	}
 
-	return nil
-}
+	cols, err := p.capture(func() error { return p.writeSelectColumns(s.Columns, allColumns) })
+	if err != nil {
+		return err
+	}
 
-func (p *writer) writeUnescapedAlphanumeric(s string) error {
-	// validate that is an identifier
-	for _, c := range s {
-		if !isIdent(byte(c), 1) {
-			return fmt.Errorf("Invalid identifier %s", s)
-		}
+	a, err := p.capture(func() error { return p.writeFromItem(from) })
+	if err != nil {
+		return err
 	}
 
-	p.buf.WriteString(s)
-	return nil
-}
+	b, err := p.capture(func() error { return p.writeJoinTarget(join) })
+	if err != nil {
+		return err
+	}
 
-func (p *writer) writeQuotedAlphanumeric(s string) error {
+	on, err := p.capture(func() error { return p.writeExpr(join.On) })
+	if err != nil {
+		return err
+	}
 
-	for i, l := 0, len(s)-1; i <= l; i++ {
-		c := s[i]
+	rest, err := p.capture(func() error { return p.writeJoins(joins[1:]) })
+	if err != nil {
+		return err
+	}
 
-		if i == 0 || i == l {
-			switch c {
-			case '\'', '"':
-				continue
-			}
+	var where queryPart
+	if s.WherePart != nil {
+		where, err = p.capture(func() error { return p.writeExpr(s.WherePart.Expr) })
+		if err != nil {
+			return err
 		}
+	}
 
-		if !isIdent(byte(c), 1) {
-			return fmt.Errorf("Invalid identifier %s", s)
-		}
+	p.buf.WriteString("SELECT " + cols.sql + " FROM " + a.sql + " LEFT JOIN " + b.sql + " ON " + on.sql + rest.sql)
+	if s.WherePart != nil {
+		p.buf.WriteString(" WHERE " + where.sql)
 	}
 
-	p.buf.WriteString(s)
+	p.buf.WriteString(" UNION ALL SELECT " + cols.sql + " FROM " + b.sql + " LEFT JOIN " + a.sql + " ON " + on.sql + rest.sql)
+	p.buf.WriteString(" WHERE NOT EXISTS (SELECT 1 FROM " + a.sql + " WHERE " + on.sql + ")")
+	if s.WherePart != nil {
+		p.buf.WriteString(" AND (" + where.sql + ")")
+	}
+
+	// the parameters of the first select stay as they are and then
+	// come the ones of the second.
+	p.repeatParams(p.paramSymbolCount, cols, b, a, on, rest, a, on, where)
 	return nil
 }
 
-func (p *writer) writeJoins(joins []*Join) error {
-	for _, j := range joins {
-		if err := p.writeJoin(j); err != nil {
-			return err
-		}
-	}
-	return nil
+// queryPart is a written part of a query and the range of the
+// parameters that it uses.
+type queryPart struct {
+	sql      string
+	from, to int
 }
 
-func (p *writer) writeJoin(join *Join) error {
-	if p.Format {
-		p.buf.WriteRune('\n')
-	} else {
-		p.buf.WriteRune(' ')
-	}
+// capture returns what f writes instead of writing it.
+func (p *writer) capture(f func() error) (queryPart, error) {
+	buf := p.buf
+	p.buf = new(bytes.Buffer)
 
-	switch join.Type {
-	case LEFT:
-		p.buf.WriteString("LEFT JOIN ")
-	case RIGHT:
-		p.buf.WriteString("RIGHT JOIN ")
-	case INNER:
-		p.buf.WriteString("INNER JOIN ")
-	case OUTER:
-		p.buf.WriteString("OUTER JOIN ")
-	case CROSS:
-		p.buf.WriteString("CROSS JOIN ")
-	case JOIN:
-		p.buf.WriteString("JOIN ")
-	default:
-		return fmt.Errorf("Invalid join type: %v", join.Type)
-	}
+	part := queryPart{from: p.paramSymbolCount}
+	err := f()
+	part.to = p.paramSymbolCount
+	part.sql = p.buf.String()
 
-	if err := p.writeTable(join.Database, join.Table, false); err != nil {
-		return err
-	}
+	p.buf = buf
+	return part, err
+}
 
-	if join.Alias != "" {
-		p.buf.WriteString(" AS ")
-		if err := p.writeIdentifier(join.Alias); err != nil {
-			return err
-		}
+// repeatParams puts the parameters of parts in the order in which they
+// are written at the position i. The parameters from i that are already
+// used by the parts are replaced. If the placeholders are numbered
+// nothing changes because they reference the original parameter.
+func (p *writer) repeatParams(i int, parts ...queryPart) {
+	if p.Dialect.Placeholder(1) != p.Dialect.Placeholder(2) {
+		return
 	}
 
-	if join.On != nil {
-		p.buf.WriteString(" ON ")
-		if err := p.writeExpr(join.On); err != nil {
-			return err
+	var params []interface{}
+	end := i
+
+	for _, part := range parts {
+		if part.to > len(p.params) {
+			return
+		}
+		params = append(params, p.params[part.from:part.to]...)
+		if part.to > end {
+			end = part.to
 		}
 	}
 
-	return nil
+	rest := p.params[end:]
+	p.params = append(append(p.params[:i:i], params...), rest...)
+	p.paramSymbolCount = i + len(params) + (p.paramSymbolCount - end)
 }
 
 func (p *writer) writeExpr(s Expr) error {
@@ -1383,6 +2685,14 @@ func (p *writer) writeExpr(s Expr) error {
 		return p.writeInExpr(t)
 	case *GroupConcatExpr:
 		return p.writeGroupConcat(t)
+	case *CaseExpr:
+		return p.writeCaseExpr(t)
+	case *ExistsExpr:
+		return p.writeExistsExpr(t)
+	case *ExcludedExpr:
+		return p.writeExcludedExpr(t)
+	case *CastExpr:
+		return p.writeCastExpr(t)
 	default:
 		return fmt.Errorf("Invalid expr %T", t)
 	}
@@ -1395,11 +2705,16 @@ func (p *writer) replaceInParameter(t *InExpr) (bool, error) {
 		return false, nil
 	}
 
-	if len(p.params) == 0 {
+	param, ok := t.Values[0].(*ParameterExpr)
+	if !ok {
 		return false, nil
 	}
 
-	if _, ok := t.Values[0].(*ParameterExpr); !ok {
+	if err := p.bindParameter(param); err != nil {
+		return true, err
+	}
+
+	if len(p.params) == 0 {
 		return false, nil
 	}
 
@@ -1510,128 +2825,294 @@ func (p *writer) isWhiteListedFunc(name string) bool {
 		}
 	}
 
-	for _, v := range list {
-		if strings.EqualFold(v, name) {
-			return true
-		}
+	for _, v := range list {
+		if strings.EqualFold(v, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *writer) writeGroupConcat(t *GroupConcatExpr) error {
+	exprs := make([]string, len(t.Expressions))
+
+	for i, exp := range t.Expressions {
+		s, err := p.exprString(exp)
+		if err != nil {
+			return err
+		}
+		exprs[i] = s
+	}
+
+	var orderBy string
+
+	if len(t.OrderByPart) > 0 {
+		buf := p.buf
+		p.buf = new(bytes.Buffer)
+		err := p.writeOrderBy(t.OrderByPart)
+		orderBy = p.buf.String()
+		p.buf = buf
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := validateSeparator(t.Separator); err != nil {
+		return err
+	}
+
+	s, err := p.Dialect.GroupConcat(t.Distinct, exprs, orderBy, t.Separator)
+	if err != nil {
+		return err
+	}
+
+	p.buf.WriteString(s)
+	return nil
+}
+
+func (p *writer) writeFuncCallExpr(t *CallExpr) error {
+	name := strings.ToUpper(t.Name)
+
+	if !p.isWhiteListedFunc(name) {
+		return fmt.Errorf("The function %s is not allowed", name)
+	}
+
+	args := make([]string, len(t.Args))
+
+	for i, a := range t.Args {
+		s, err := p.exprString(a)
+		if err != nil {
+			return err
+		}
+		args[i] = s
+	}
+
+	s, ok, err := p.Dialect.Func(name, args)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		p.buf.WriteString(s)
+	} else {
+		p.buf.WriteString(name)
+		p.buf.WriteRune('(')
+		p.buf.WriteString(strings.Join(args, ", "))
+		p.buf.WriteRune(')')
+	}
+
+	if t.Over != nil {
+		p.buf.WriteString(" OVER ")
+		if err := p.writeOver(t.Over); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *writer) writeWindows(windows []*WindowDef) error {
+	if !p.Dialect.Supports(WindowFunctions) {
+		return fmt.Errorf("Window functions are not supported in %s", p.driver)
+	}
+
+	p.buf.WriteString("WINDOW ")
+
+	for i, w := range windows {
+		if i > 0 {
+			p.buf.WriteString(", ")
+		}
+
+		if err := p.writeIdentifier(w.Name); err != nil {
+			return err
+		}
+
+		p.buf.WriteString(" AS ")
+
+		// a window definition is always written between parenthesis
+		// even if it only references another window.
+		if err := p.writeWindowSpec(w.Over); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *writer) writeOver(t *OverClause) error {
+	if !p.Dialect.Supports(WindowFunctions) {
+		return fmt.Errorf("Window functions are not supported in %s at %v", p.driver, t.Pos)
+	}
+
+	// only a reference to a named window
+	if t.Name != "" && t.isEmpty() {
+		return p.writeIdentifier(t.Name)
 	}
 
-	return false
+	return p.writeWindowSpec(t)
 }
 
-func (p *writer) writeGroupConcat(t *GroupConcatExpr) error {
-	if p.driver == "sqlite3" {
-		return p.writeGroupConcatSqlite(t)
-	}
+func (p *writer) writeWindowSpec(t *OverClause) error {
+	p.buf.WriteRune('(')
 
-	p.buf.WriteString("GROUP_CONCAT(")
+	var space bool
 
-	if t.Distinct {
-		p.buf.WriteString("DISTINCT ")
+	if t.Name != "" {
+		if err := p.writeIdentifier(t.Name); err != nil {
+			return err
+		}
+		space = true
 	}
 
-	for i, exp := range t.Expressions {
-		if i > 0 {
-			p.buf.WriteRune(',')
+	if len(t.PartitionBy) > 0 {
+		if space {
+			p.buf.WriteRune(' ')
 		}
-		if err := p.writeExpr(exp); err != nil {
-			return err
+
+		p.buf.WriteString("PARTITION BY ")
+
+		for i, e := range t.PartitionBy {
+			if i > 0 {
+				p.buf.WriteString(", ")
+			}
+			if err := p.writeExpr(e); err != nil {
+				return err
+			}
 		}
+		space = true
 	}
 
 	if len(t.OrderByPart) > 0 {
-		p.buf.WriteRune(' ')
+		if space {
+			p.buf.WriteRune(' ')
+		}
+
 		if err := p.writeOrderBy(t.OrderByPart); err != nil {
 			return err
 		}
+		space = true
 	}
 
-	if t.Separator != "" {
-		p.buf.WriteString(` SEPARATOR '`)
-		if err := validateSeparator(t.Separator); err != nil {
+	if t.Frame != nil {
+		if space {
+			p.buf.WriteRune(' ')
+		}
+
+		if err := p.writeWindowFrame(t.Frame); err != nil {
 			return err
 		}
-		p.buf.WriteString(t.Separator)
-		p.buf.WriteRune('\'')
 	}
 
 	p.buf.WriteRune(')')
 	return nil
 }
 
-func (p *writer) writeGroupConcatSqlite(t *GroupConcatExpr) error {
-	p.buf.WriteString("GROUP_CONCAT(")
+func (p *writer) writeWindowFrame(t *WindowFrame) error {
+	switch t.Unit {
+	case "ROWS", "RANGE":
+		p.buf.WriteString(t.Unit)
+	default:
+		return fmt.Errorf("Invalid window frame %s", t.Unit)
+	}
 
-	for i, exp := range t.Expressions {
-		if i > 0 {
-			p.buf.WriteRune(',')
-		}
-		if err := p.writeExpr(exp); err != nil {
-			return err
-		}
+	p.buf.WriteRune(' ')
+
+	if t.End == nil {
+		return p.writeFrameBound(t.Start)
 	}
 
-	p.buf.WriteRune(')')
-	return nil
-}
+	p.buf.WriteString("BETWEEN ")
 
-func (p *writer) writeFuncCallExpr(t *CallExpr) error {
-	name := strings.ToUpper(t.Name)
+	if err := p.writeFrameBound(t.Start); err != nil {
+		return err
+	}
 
-	if !p.isWhiteListedFunc(name) {
-		return fmt.Errorf("The function %s is not allowed", name)
+	p.buf.WriteString(" AND ")
+
+	return p.writeFrameBound(t.End)
+}
+
+func (p *writer) writeFrameBound(t *FrameBound) error {
+	if t == nil {
+		return fmt.Errorf("Invalid window frame")
 	}
 
-	switch name {
-	case "CONCAT", "CONCAT_WS": // _ws is not the same but use whats available.
-		if p.driver == "sqlite3" {
-			return p.writeConcatSqlite(t)
+	switch t.Type {
+	case "UNBOUNDED PRECEDING", "UNBOUNDED FOLLOWING", "CURRENT ROW":
+		p.buf.WriteString(t.Type)
+	case "PRECEDING", "FOLLOWING":
+		if t.Offset == nil {
+			return fmt.Errorf("Expected an offset in %s", t.Type)
 		}
-	case "UTC_TIMESTAMP":
-		if p.driver == "sqlite3" {
-			return p.writeUTCTimestampSqlite(t)
+		if err := p.writeExpr(t.Offset); err != nil {
+			return err
 		}
+		p.buf.WriteRune(' ')
+		p.buf.WriteString(t.Type)
+	default:
+		return fmt.Errorf("Invalid window frame %s", t.Type)
 	}
 
-	p.buf.WriteString(name)
+	return nil
+}
 
-	p.buf.WriteRune('(')
+func (p *writer) writeExistsExpr(t *ExistsExpr) error {
+	if t.Query == nil {
+		return fmt.Errorf("Expected a subquery at %v", t.Pos)
+	}
 
-	for i, a := range t.Args {
-		if i > 0 {
-			p.buf.WriteString(", ")
-		}
+	if t.Not {
+		p.buf.WriteString("NOT ")
+	}
 
-		if err := p.writeExpr(a); err != nil {
-			return err
-		}
+	p.buf.WriteString("EXISTS (")
+
+	// the subquery changes the current query.
+	current := p.currentQuery
+	if err := p.writeSelect(t.Query); err != nil {
+		return err
 	}
+	p.currentQuery = current
 
 	p.buf.WriteRune(')')
-
 	return nil
 }
 
-func (p *writer) writeUTCTimestampSqlite(t *CallExpr) error {
-	if len(t.Args) > 0 {
-		return fmt.Errorf("Expected 0 args")
+func (p *writer) writeCaseExpr(t *CaseExpr) error {
+	p.buf.WriteString("CASE")
+
+	if t.Value != nil {
+		p.buf.WriteRune(' ')
+		if err := p.writeExpr(t.Value); err != nil {
+			return err
+		}
 	}
 
-	p.buf.WriteString("datetime('now')")
-	return nil
-}
+	if len(t.Whens) == 0 {
+		return fmt.Errorf("Expected at least one WHEN at %v", t.Pos)
+	}
 
-func (p *writer) writeConcatSqlite(t *CallExpr) error {
-	for i, a := range t.Args {
-		if i > 0 {
-			p.buf.WriteString(" || ")
+	for _, w := range t.Whens {
+		p.buf.WriteString(" WHEN ")
+		if err := p.writeExpr(w.Cond); err != nil {
+			return err
 		}
 
-		if err := p.writeExpr(a); err != nil {
+		p.buf.WriteString(" THEN ")
+		if err := p.writeExpr(w.Result); err != nil {
+			return err
+		}
+	}
+
+	if t.Else != nil {
+		p.buf.WriteString(" ELSE ")
+		if err := p.writeExpr(t.Else); err != nil {
 			return err
 		}
 	}
 
+	p.buf.WriteString(" END")
 	return nil
 }
 
@@ -1743,10 +3224,22 @@ func (p *writer) writeUnaryExpr(t *UnaryExpr) error {
 		p.buf.WriteRune('+')
 	case SUB:
 		p.buf.WriteRune('-')
+	case NTB:
+		p.buf.WriteRune('~')
 	default:
 		return fmt.Errorf("Invalid unary operator %v", t.Operator)
 	}
 
+	// a space prevents writing -- that starts a comment
+	switch e := t.Operand.(type) {
+	case *UnaryExpr:
+		p.buf.WriteRune(' ')
+	case *ConstantExpr:
+		if strings.HasPrefix(e.Value, "-") {
+			p.buf.WriteRune(' ')
+		}
+	}
+
 	err := p.writeExpr(t.Operand)
 	if err != nil {
 		return err
@@ -1767,10 +3260,15 @@ func (p *writer) handleEmptyIN(t *BinaryExpr) (bool, error) {
 		return false, nil
 	}
 
-	if _, ok := inExp.Values[0].(*ParameterExpr); !ok {
+	param, ok := inExp.Values[0].(*ParameterExpr)
+	if !ok {
 		return false, nil
 	}
 
+	if err := p.bindParameter(param); err != nil {
+		return false, err
+	}
+
 	var isEmpty bool
 
 	if len(p.params) == 0 {
@@ -1808,6 +3306,10 @@ func (p *writer) handleNullEquality(t *BinaryExpr, equals bool) (bool, error) {
 
 	switch t := t.Right.(type) {
 	case *ParameterExpr:
+		if err := p.bindParameter(t); err != nil {
+			return false, err
+		}
+
 		if len(p.params) <= p.paramSymbolCount {
 			isNull = true
 		} else {
@@ -1866,9 +3368,19 @@ func (p *writer) writeBinaryExpr(t *BinaryExpr) error {
 		if ok {
 			return nil
 		}
+	case LIKE, NOTLIKE, ILIKE, NOTILIKE, REGEXP, NOTREGEXP, GLOB, NOTGLOB:
+		return p.writeMatch(t)
+	case ISDISTINCT, ISNOTDISTINCT:
+		return p.writeIsDistinct(t)
+	case CONCAT:
+		return p.writeConcat(t)
+	case XOB:
+		if p.Dialect.XorOperator() == "" {
+			return p.emulateXor(t)
+		}
 	}
 
-	err := p.writeExpr(t.Left)
+	err := p.writeOperand(t.Operator, t.Left)
 	if err != nil {
 		return err
 	}
@@ -1887,9 +3399,15 @@ func (p *writer) writeBinaryExpr(t *BinaryExpr) error {
 	case MOD:
 		p.buf.WriteRune('%')
 	case LSF:
+		p.buf.WriteString("<<")
+	case RSF:
 		p.buf.WriteString(">>")
 	case ANB:
 		p.buf.WriteRune('&')
+	case ORB:
+		p.buf.WriteRune('|')
+	case XOB:
+		p.buf.WriteString(p.Dialect.XorOperator())
 	case LSS:
 		p.buf.WriteRune('<')
 	case LEQ:
@@ -1900,10 +3418,6 @@ func (p *writer) writeBinaryExpr(t *BinaryExpr) error {
 		p.buf.WriteString(">=")
 	case EQL:
 		p.buf.WriteRune('=')
-	case LIKE:
-		p.buf.WriteString("LIKE")
-	case NOTLIKE:
-		p.buf.WriteString("NOT LIKE")
 	case NEQ:
 		p.buf.WriteString("!=")
 	case AND:
@@ -1932,11 +3446,159 @@ func (p *writer) writeBinaryExpr(t *BinaryExpr) error {
 
 	p.buf.WriteRune(' ')
 
-	err = p.writeExpr(t.Right)
+	err = p.writeOperand(t.Operator, t.Right)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// writeOperand writes an operand of a binary expression. The precedence
+// of the bitwise operators is different in each database so they are
+// written in parentheses.
+func (p *writer) writeOperand(op Type, e Expr) error {
+	b, ok := e.(*BinaryExpr)
+	if !ok || !isBitwise(op) && !isBitwise(b.Operator) {
+		return p.writeExpr(e)
+	}
+
+	// CONCAT is a function
+	if b.Operator == CONCAT && p.Dialect.ConcatOperator() == "" {
+		return p.writeExpr(e)
+	}
+
+	p.buf.WriteRune('(')
+	if err := p.writeExpr(e); err != nil {
+		return err
+	}
+	p.buf.WriteRune(')')
+	return nil
+}
+
+func isBitwise(op Type) bool {
+	switch op {
+	case ANB, ORB, XOB, LSF, RSF, CONCAT:
+		return true
+	}
+	return false
+}
+
+// writeMatch writes LIKE, ILIKE, REGEXP or GLOB and their negations.
+func (p *writer) writeMatch(t *BinaryExpr) error {
+	op, not := t.Operator, false
+	switch op {
+	case NOTLIKE:
+		op, not = LIKE, true
+	case NOTILIKE:
+		op, not = ILIKE, true
+	case NOTREGEXP:
+		op, not = REGEXP, true
+	case NOTGLOB:
+		op, not = GLOB, true
+	}
+
+	pattern, escape := t.Right, Expr(nil)
+	if e, ok := t.Right.(*EscapeExpr); ok {
+		if op != LIKE && op != ILIKE {
+			return fmt.Errorf("Invalid ESCAPE in %v", t.Operator)
+		}
+		pattern, escape = e.Pattern, e.Escape
+	}
+
+	expr, err := p.capture(func() error { return p.writeOperand(t.Operator, t.Left) })
 	if err != nil {
 		return err
 	}
 
+	pat, err := p.capture(func() error { return p.writeOperand(t.Operator, pattern) })
+	if err != nil {
+		return err
+	}
+
+	var esc queryPart
+	if escape != nil {
+		esc, err = p.capture(func() error { return p.writeOperand(t.Operator, escape) })
+		if err != nil {
+			return err
+		}
+	}
+
+	s, err := p.Dialect.Match(op, not, expr.sql, pat.sql, esc.sql)
+	if err != nil {
+		return err
+	}
+
+	p.buf.WriteString(s)
+	return nil
+}
+
+// writeIsDistinct writes IS [NOT] DISTINCT FROM.
+func (p *writer) writeIsDistinct(t *BinaryExpr) error {
+	left, err := p.capture(func() error { return p.writeOperand(t.Operator, t.Left) })
+	if err != nil {
+		return err
+	}
+
+	right, err := p.capture(func() error { return p.writeOperand(t.Operator, t.Right) })
+	if err != nil {
+		return err
+	}
+
+	p.buf.WriteString(p.Dialect.IsDistinct(t.Operator == ISNOTDISTINCT, left.sql, right.sql))
+	return nil
+}
+
+// writeConcat writes a || b || c. MySQL translates it to CONCAT.
+func (p *writer) writeConcat(t *BinaryExpr) error {
+	var exprs []string
+
+	op := p.Dialect.ConcatOperator()
+
+	for _, e := range concatOperands(t, nil) {
+		part, err := p.capture(func() error {
+			if op == "" {
+				return p.writeExpr(e)
+			}
+			return p.writeOperand(CONCAT, e)
+		})
+		if err != nil {
+			return err
+		}
+		exprs = append(exprs, part.sql)
+	}
+
+	if op == "" {
+		p.buf.WriteString("CONCAT(" + strings.Join(exprs, ", ") + ")")
+	} else {
+		p.buf.WriteString(strings.Join(exprs, " "+op+" "))
+	}
+	return nil
+}
+
+// concatOperands returns the operands of nested || in order.
+func concatOperands(e Expr, exprs []Expr) []Expr {
+	if b, ok := e.(*BinaryExpr); ok && b.Operator == CONCAT {
+		return concatOperands(b.Right, concatOperands(b.Left, exprs))
+	}
+	return append(exprs, e)
+}
+
+// emulateXor writes a ^ b as (a | b) - (a & b) for the databases that
+// don't have a XOR operator.
+func (p *writer) emulateXor(t *BinaryExpr) error {
+	a, err := p.capture(func() error { return p.writeOperand(XOB, t.Left) })
+	if err != nil {
+		return err
+	}
+
+	b, err := p.capture(func() error { return p.writeOperand(XOB, t.Right) })
+	if err != nil {
+		return err
+	}
+
+	p.buf.WriteString("(" + a.sql + " | " + b.sql + ") - (" + a.sql + " & " + b.sql + ")")
+	p.repeatParams(a.from, a, b, a, b)
 	return nil
 }
 
@@ -1952,35 +3614,174 @@ func (p *writer) writeAllColumnsExpr(t *AllColumnsExpr) error {
 }
 
 func (p *writer) writeParameterExpr(t *ParameterExpr) error {
-	p.buf.WriteRune('?')
+	if err := p.bindParameter(t); err != nil {
+		return err
+	}
+
+	// Parameters removed from the query (IN values, nulls...) don't increment
+	// the count so the numbers are always correlative.
 	p.paramSymbolCount++
+	p.buf.WriteString(p.Dialect.Placeholder(p.paramSymbolCount))
+	return nil
+}
+
+// bindParameter adds the value of a named parameter to the positional
+// parameters so it is handled like a '?'. A name can be repeated and
+// each time it is added again.
+//
+// The values are passed as the only parameter and can be a map with
+// string keys or a struct. The fields of a struct are matched by the
+// tag `db` or by the name ignoring the case.
+func (p *writer) bindParameter(t *ParameterExpr) error {
+	if t.Name == "" {
+		if p.named != nil {
+			return fmt.Errorf("Can't mix named and positional parameters at %v", t.Pos)
+		}
+		return nil
+	}
+
+	if p.named == nil {
+		if p.paramSymbolCount > 0 || len(p.params) != 1 || !isNamedValues(p.params[0]) {
+			return fmt.Errorf("Expected a map or struct with the value of %s at %v", t.Name, t.Pos)
+		}
+		p.named = p.params[0]
+		p.params = nil
+	}
+
+	// the parameter was already added by a previous check
+	if len(p.params) > p.paramSymbolCount {
+		return nil
+	}
+
+	v, ok := namedValue(p.named, t.Name)
+	if !ok {
+		return fmt.Errorf("Missing parameter %s at %v", t.Name, t.Pos)
+	}
+
+	p.params = append(p.params, v)
 	return nil
 }
 
+func isNamedValues(v interface{}) bool {
+	r := reflect.Indirect(reflect.ValueOf(v))
+	switch r.Kind() {
+	case reflect.Map:
+		return r.Type().Key().Kind() == reflect.String
+	case reflect.Struct:
+		return true
+	}
+	return false
+}
+
+func namedValue(values interface{}, name string) (interface{}, bool) {
+	r := reflect.Indirect(reflect.ValueOf(values))
+
+	if r.Kind() == reflect.Map {
+		v := r.MapIndex(reflect.ValueOf(name).Convert(r.Type().Key()))
+		if !v.IsValid() {
+			return nil, false
+		}
+		return v.Interface(), true
+	}
+
+	typ := r.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+
+		tag := f.Tag.Get("db")
+		if tag == name || tag == "" && strings.EqualFold(f.Name, name) {
+			return r.Field(i).Interface(), true
+		}
+	}
+
+	return nil, false
+}
+
+// isNumber returns true if s is a decimal number: -12, 1.5, .5 or 1.5e-3.
+func isNumber(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+
+	i := strings.IndexAny(s, "eE")
+	if i != -1 {
+		exp := strings.TrimLeft(s[i+1:], "+-")
+		if len(s[i+1:])-len(exp) > 1 || !isDigits(exp) {
+			return false
+		}
+		s = s[:i]
+	}
+
+	if i := strings.IndexByte(s, '.'); i != -1 {
+		// one of the parts can be empty: .5 or 1.
+		a, b := s[:i], s[i+1:]
+		return (a != "" || b != "") && (a == "" || isDigits(a)) && (b == "" || isDigits(b))
+	}
+
+	return isDigits(s)
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isDecimal(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isBinary validates the digits of a HEX, BIT or HEXSTRING constant.
+func isBinary(kind Type, s string) bool {
+	digit := isHex
+	if kind == BIT {
+		digit = isBit
+	}
+
+	if kind == HEX && s == "" || kind == HEXSTRING && len(s)%2 != 0 {
+		return false
+	}
+
+	// HEX and BIT are 64 bit numbers in all databases.
+	if kind == HEX && len(s) > 16 || kind == BIT && len(s) > 64 {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if !digit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 func (p *writer) writeConstantExpr(t *ConstantExpr) error {
 	switch t.Kind {
 	case INT, FLOAT:
+		if !isNumber(t.Value) {
+			return fmt.Errorf("Invalid number %s at %v", t.Value, t.Position())
+		}
 		p.buf.WriteString(t.Value)
+	case HEX, BIT, HEXSTRING:
+		if !isBinary(t.Kind, t.Value) {
+			return fmt.Errorf("Invalid number %s at %v", t.Value, t.Position())
+		}
+		s, err := p.Dialect.BinaryLiteral(t.Kind, t.Value)
+		if err != nil {
+			return err
+		}
+		p.buf.WriteString(s)
 	case STRING:
-		p.buf.WriteRune('"')
-		p.buf.WriteString(sanitize(t.Value))
-		p.buf.WriteRune('"')
+		p.buf.WriteString(p.Dialect.StringLiteral(t.Value))
 	case NULL:
 		p.buf.WriteString("null")
 	case TRUE:
-		switch p.driver {
-		case "sqlite3":
-			p.buf.WriteString("1")
-		default:
-			p.buf.WriteString("true")
-		}
+		p.buf.WriteString(p.Dialect.BoolLiteral(true))
 	case FALSE:
-		switch p.driver {
-		case "sqlite3":
-			p.buf.WriteString("0")
-		default:
-			p.buf.WriteString("false")
-		}
+		p.buf.WriteString(p.Dialect.BoolLiteral(false))
 	case DEFAULT:
 		p.buf.WriteString("default")
 	default:
@@ -1988,7 +3789,3 @@ func (p *writer) writeConstantExpr(t *ConstantExpr) error {
 	}
 	return nil
 }
-
-func sanitize(s string) string {
-	return strings.Replace(s, "\"", "\\\"", -1)
-}
@@ -442,7 +442,7 @@ func TestParseSelect1(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if s != `SELECT 1 AS num, true, false, null, "te\"st"` {
+	if s != `SELECT 1 AS num, true, false, null, 'te"st'` {
 		t.Fatal(s)
 	}
 }
@@ -758,7 +758,7 @@ func TestParseWhereIN2(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if s != `SELECT 1 FROM foo WHERE id IN ("aa", "bb")` {
+	if s != `SELECT 1 FROM foo WHERE id IN ('aa', 'bb')` {
 		t.Fatal(s)
 	}
 }
@@ -886,7 +886,7 @@ func TestParseDelete(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if s != `DELETE FROM z.foo WHERE x = "foo" AND r = "bar" LIMIT 3` {
+	if s != `DELETE FROM z.foo WHERE x = 'foo' AND r = 'bar' LIMIT 3` {
 		t.Fatal(s)
 	}
 }
@@ -934,7 +934,7 @@ func TestParseUpdate3(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if s != `UPDATE post SET title = CONCAT(title, "-Z")` {
+	if s != `UPDATE post SET title = CONCAT(title, '-Z')` {
 		t.Fatal(s)
 	}
 }
@@ -1099,6 +1099,56 @@ func TestParseUpdateJoinSqlite(t *testing.T) {
 	}
 }
 
+func TestParseUpdateJoinPostgres(t *testing.T) {
+	queries := []string{
+		"UPDATE a JOIN b ON a.id = b.ida SET status = ? WHERE id = ?",
+		"DELETE a FROM a JOIN b ON a.id = b.ida WHERE b.id = ?",
+	}
+
+	for _, query := range queries {
+		q, err := ParseQuery(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s, _, err := toSQL(false, q, nil, "", "postgres")
+		if err == nil {
+			t.Fatalf("Expected failure in postgres: %v", s)
+		}
+
+		if !strings.Contains(err.Error(), "UPDATE JOIN not supported in postgres") {
+			t.Fatalf("Unexpected error got %v", err)
+		}
+	}
+}
+
+func TestUpdateLimitPostgres(t *testing.T) {
+	queries := []string{
+		"UPDATE a SET status = 1 WHERE id > ? LIMIT 3",
+		"DELETE FROM a WHERE id > ? LIMIT 3",
+	}
+
+	for _, query := range queries {
+		q, err := ParseQuery(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s, _, err := toSQL(false, q, []interface{}{1}, "", "mysql"); err != nil || !strings.HasSuffix(s, " LIMIT 3") {
+			t.Fatal(s, err)
+		}
+
+		s, _, err := toSQL(false, q, []interface{}{1}, "", "postgres")
+		if err == nil {
+			t.Fatalf("Expected failure in postgres: %v", s)
+		}
+
+		if !strings.Contains(err.Error(), "LIMIT not supported in postgres") {
+			t.Fatalf("Unexpected error got %v", err)
+		}
+	}
+}
+
 func TestParseUpdateAlias(t *testing.T) {
 	q, err := ParseQuery("UPDATE aa a JOIN bb b ON a.id = b.ida SET status=? WHERE id=?")
 	if err != nil {
@@ -1319,6 +1369,165 @@ func TestParseInsert5(t *testing.T) {
 	}
 }
 
+func TestParseInsertRows(t *testing.T) {
+	q, err := ParseQuery("insert into foo (a, b) values (?, 1), (2, ?), (?, ?)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := []interface{}{"a1", "b2", "a3", "b3"}
+
+	s, ps, err := toSQL(false, q, params, "", "postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "INSERT INTO foo (a, b) VALUES ($1, 1), (2, $2), ($3, $4)" {
+		t.Fatal(s)
+	}
+
+	if len(ps) != 4 || ps[0] != "a1" || ps[1] != "b2" || ps[2] != "a3" || ps[3] != "b3" {
+		t.Fatal(ps)
+	}
+}
+
+func TestParseInsertRowsCount(t *testing.T) {
+	_, err := ParseQuery("insert into foo (a, b) values (1, 2), (3)")
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+
+	_, err = ParseQuery("insert into foo values (1, 2), (3, 4, 5)")
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+}
+
+func TestUpsertMySQL(t *testing.T) {
+	q, err := ParseQuery("insert into foo (id, a) values (?, ?) on conflict (id) do update set a = excluded.a, b = b + 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, []interface{}{1, 2}, "", "mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "INSERT INTO foo (id, a) VALUES (?, ?) ON DUPLICATE KEY UPDATE a = VALUES(a), b = b + 1" {
+		t.Fatal(s)
+	}
+}
+
+func TestUpsertSqlite(t *testing.T) {
+	q, err := ParseQuery("insert into foo (id, a) values (?, ?) on duplicate key update a = values(a)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, []interface{}{1, 2}, "", "sqlite3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "INSERT INTO foo (id, a) VALUES (?, ?) ON CONFLICT DO UPDATE SET a = excluded.a" {
+		t.Fatal(s)
+	}
+
+	// the conflict target is required before sqlite 3.35
+	w := NewWriter(q, []interface{}{1, 2}, "", "sqlite3")
+	w.Dialect = SQLiteDialect{Version: "3.34"}
+	if _, _, err := w.Write(); err == nil {
+		t.Fatal("Expected error")
+	}
+
+	q.(*InsertQuery).OnConflict.Columns = []string{"id"}
+
+	s, _, err = toSQL(false, q, []interface{}{1, 2}, "", "sqlite3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "INSERT INTO foo (id, a) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET a = excluded.a" {
+		t.Fatal(s)
+	}
+}
+
+func TestUpsertPostgres(t *testing.T) {
+	q, err := ParseQuery("insert into foo (id, a) values (?, ?) on duplicate key update a = values(a)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// postgres doesn't know the keys of the table
+	_, _, err = toSQL(false, q, []interface{}{1, 2}, "", "postgres")
+	if err == nil || !strings.Contains(err.Error(), "requires the conflict columns in postgres") {
+		t.Fatal(err)
+	}
+
+	q.(*InsertQuery).OnConflict.Columns = []string{"id"}
+
+	s, _, err := toSQL(false, q, []interface{}{1, 2}, "", "postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "INSERT INTO foo (id, a) VALUES ($1, $2) ON CONFLICT (id) DO UPDATE SET a = excluded.a" {
+		t.Fatal(s)
+	}
+}
+
+func TestUpsertPostgresParams(t *testing.T) {
+	q, err := ParseQuery("insert into foo (id, a) values (?, ?) on conflict (id) do update set a = ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, ps, err := toSQL(false, q, []interface{}{1, 2, 3}, "", "postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "INSERT INTO foo (id, a) VALUES ($1, $2) ON CONFLICT (id) DO UPDATE SET a = $3" {
+		t.Fatal(s)
+	}
+
+	if len(ps) != 3 || ps[2] != 3 {
+		t.Fatal(ps)
+	}
+}
+
+func TestUpsertDoNothing(t *testing.T) {
+	q, err := ParseQuery("insert into foo (id, a) values (1, 2) on conflict (id) do nothing")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, nil, "", "sqlite3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "INSERT INTO foo (id, a) VALUES (1, 2) ON CONFLICT (id) DO NOTHING" {
+		t.Fatal(s)
+	}
+
+	s, _, err = toSQL(false, q, nil, "", "mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "INSERT INTO foo (id, a) VALUES (1, 2) ON DUPLICATE KEY UPDATE id = id" {
+		t.Fatal(s)
+	}
+}
+
+func TestExcludedOutsideUpsert(t *testing.T) {
+	if _, err := ParseQuery("select values(a) from foo"); err == nil {
+		t.Fatal("Expected error")
+	}
+}
+
 func TestParseIgnoreNamespaces(t *testing.T) {
 	q, err := ParseQuery("select * from foo:bar:buzz")
 	if err != nil {
@@ -1338,6 +1547,1165 @@ func TestParseIgnoreNamespaces(t *testing.T) {
 	}
 }
 
+func TestPostgresParams(t *testing.T) {
+	q, err := ParseQuery("select * from foo where a = ? and id in ? and b = ? and c = ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := []interface{}{1, []interface{}{1, 2}, nil, "x"}
+
+	s, params, err := toSQL(false, q, params, "", "postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT * FROM foo WHERE a = $1 AND id IN (1, 2) AND b IS NULL AND c = $2` {
+		t.Fatal(s)
+	}
+
+	if len(params) != 2 || params[0] != 1 || params[1] != "x" {
+		t.Fatal(params)
+	}
+}
+
+func TestPostgresLimit(t *testing.T) {
+	q, err := ParseQuery("select * from foo where a = ? limit ?, ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, []interface{}{1, 20, 10}, "", "postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT * FROM foo WHERE a = $1 LIMIT $3 OFFSET $2` {
+		t.Fatal(s)
+	}
+}
+
+func TestPostgresEscapeIdents(t *testing.T) {
+	q, err := ParseQuery("select f.name, 'it\\'s' from foo f")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := NewWriter(q, nil, "db", "postgres").Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT "f"."name", 'it''s' FROM "db"."foo" AS "f"` {
+		t.Fatal(s)
+	}
+}
+
+func TestPostgresGroupConcat(t *testing.T) {
+	q, err := ParseQuery("select group_concat(distinct v order by v asc separator ';') from t")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, nil, "", "postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT STRING_AGG(DISTINCT CAST(v AS TEXT), ';' ORDER BY v ASC) FROM t` {
+		t.Fatal(s)
+	}
+}
+
+func TestCaseExpr(t *testing.T) {
+	q, err := ParseQuery(`select case when status = 1 then 'open' else 'closed' end as s
+						  from foo
+						  where case status when 1 then ? else 0 end = 1
+						  group by case when a > 0 then 1 end
+						  order by case when b is null then 0 else 1 end desc`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, nil, "", "sqlite3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT CASE WHEN status = 1 THEN 'open' ELSE 'closed' END AS s`+
+		` FROM foo`+
+		` WHERE CASE status WHEN 1 THEN ? ELSE 0 END = 1`+
+		` GROUP BY CASE WHEN a > 0 THEN 1 END`+
+		` ORDER BY CASE WHEN b IS null THEN 0 ELSE 1 END DESC` {
+		t.Fatal(s)
+	}
+}
+
+func TestCaseExprUpdate(t *testing.T) {
+	q, err := ParseQuery("update foo set a = case when b > 1 then (select max(id) from bar) else a end")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWriter(q, nil, "", "sqlite3")
+	w.Namespace = "ns"
+	w.EscapeIdents = false
+
+	s, _, err := w.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `UPDATE ns_foo SET a = CASE WHEN b > 1 THEN (SELECT MAX(id) FROM ns_bar) ELSE a END` {
+		t.Fatal(s)
+	}
+}
+
+func TestCaseExprWhitelist(t *testing.T) {
+	q, err := ParseQuery("select case when a = 1 then sleep(10) end from foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWriter(q, nil, "", "")
+	w.WhitelistFuncs = []string{"max"}
+
+	if _, _, err := w.Write(); err == nil {
+		t.Fatal("Expected a not allowed function error")
+	}
+}
+
+func TestCaseExprColumns(t *testing.T) {
+	q, err := ParseQuery("select case, end, case when then = 1 then else end from foo where case = 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, nil, "", "sqlite3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT case, end, CASE WHEN then = 1 THEN else END FROM foo WHERE case = 1` {
+		t.Fatal(s)
+	}
+}
+
+func TestCaseExprInvalid(t *testing.T) {
+	queries := []string{
+		"select case end from foo",
+		"select case when a then 1 from foo",
+		"select case when a 1 end from foo",
+	}
+
+	for i, q := range queries {
+		if _, err := ParseQuery(q); err == nil {
+			t.Fatalf("%d: Expected invalid query: %s", i, q)
+		}
+	}
+}
+
+func TestExists(t *testing.T) {
+	q, err := ParseQuery(`select * from foo f where exists (select 1 from bar b where b.id = f.id)
+						  and not exists (select * from baz where x = ?)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWriter(q, []interface{}{1}, "", "sqlite3")
+	w.Namespace = "ns"
+	w.EscapeIdents = false
+
+	s, _, err := w.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT * FROM ns_foo AS f WHERE EXISTS (SELECT 1 FROM ns_bar AS b WHERE b.id = f.id)`+
+		` AND NOT EXISTS (SELECT * FROM ns_baz WHERE x = ?)` {
+		t.Fatal(s)
+	}
+}
+
+func TestExistsDelete(t *testing.T) {
+	q, err := ParseQuery("delete from foo where not exists (select 1 from bar where bar.id = foo.id) and a = ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWriter(q, []interface{}{1}, "", "sqlite3")
+	w.Namespace = "ns"
+	w.EscapeIdents = false
+
+	s, _, err := w.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `DELETE FROM ns_foo WHERE NOT EXISTS (SELECT 1 FROM ns_bar WHERE bar.id = foo.id) AND a = ?` {
+		t.Fatal(s)
+	}
+}
+
+func TestWith(t *testing.T) {
+	q, err := ParseQuery(`with recent as (select * from orders where created > ?),
+						  totals (account, total) as (select account, sum(amount) from recent group by account)
+						  select * from totals t join accounts a on a.id = t.account`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWriter(q, []interface{}{1}, "db", "mysql")
+	w.Namespace = "ns"
+	w.EscapeIdents = false
+
+	s, _, err := w.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `WITH recent AS (SELECT * FROM db.ns_orders WHERE created > ?), `+
+		`totals (account, total) AS (SELECT account, SUM(amount) FROM recent GROUP BY account) `+
+		`SELECT * FROM totals AS t JOIN db.ns_accounts AS a ON a.id = t.account` {
+		t.Fatal(s)
+	}
+}
+
+func TestWithRecursive(t *testing.T) {
+	q, err := Select(`with recursive tree as (
+						select id, parent from nodes where id = ?
+						union select n.id, n.parent from nodes n join tree on n.parent = tree.id
+					  )
+					  select id from tree`, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWriter(q, q.Params, "", "sqlite3")
+	w.Namespace = "ns"
+	w.EscapeIdents = false
+
+	s, _, err := w.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `WITH RECURSIVE tree AS (SELECT id, parent FROM ns_nodes WHERE id = ? `+
+		`UNION SELECT n.id, n.parent FROM ns_nodes AS n JOIN tree ON n.parent = tree.id) `+
+		`SELECT id FROM tree` {
+		t.Fatal(s)
+	}
+}
+
+func TestWithSameName(t *testing.T) {
+	q, err := ParseQuery(`with users as (select * from users where active = 1) select * from users`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWriter(q, nil, "", "sqlite3")
+	w.Namespace = "ns"
+	w.EscapeIdents = false
+
+	s, _, err := w.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `WITH users AS (SELECT * FROM ns_users WHERE active = 1) SELECT * FROM users` {
+		t.Fatal(s)
+	}
+}
+
+func TestWithColumn(t *testing.T) {
+	q, err := ParseQuery(`with w as (select with from foo) select with from w`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, nil, "", "sqlite3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `WITH w AS (SELECT with FROM foo) SELECT with FROM w` {
+		t.Fatal(s)
+	}
+
+	if _, err := ParseQuery(`with from foo`); err == nil {
+		t.Fatal("Expected an invalid query")
+	}
+}
+
+func TestWithDelete(t *testing.T) {
+	q, err := ParseQuery(`with old as (select id from logs where created < ?) delete from logs where id in (select id from old)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWriter(q, []interface{}{1}, "", "sqlite3")
+	w.Namespace = "ns"
+	w.EscapeIdents = false
+
+	s, _, err := w.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `WITH old AS (SELECT id FROM ns_logs WHERE created < ?) DELETE FROM ns_logs WHERE id IN (SELECT id FROM old)` {
+		t.Fatal(s)
+	}
+}
+
+func TestWindowFunction(t *testing.T) {
+	q, err := ParseQuery("SELECT id, ROW_NUMBER() OVER (PARTITION BY account ORDER BY created DESC) FROM logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, nil, "", "mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT id, ROW_NUMBER() OVER (PARTITION BY account ORDER BY created DESC) FROM logs` {
+		t.Fatal(s)
+	}
+}
+
+func TestWindowFrame(t *testing.T) {
+	q, err := ParseQuery("SELECT SUM(amount) OVER (ORDER BY id ROWS BETWEEN 2 PRECEDING AND CURRENT ROW), " +
+		"AVG(amount) OVER (ORDER BY id RANGE UNBOUNDED PRECEDING) FROM sales")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, nil, "", "sqlite3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT SUM(amount) OVER (ORDER BY id ROWS BETWEEN 2 PRECEDING AND CURRENT ROW), `+
+		`AVG(amount) OVER (ORDER BY id RANGE UNBOUNDED PRECEDING) FROM sales` {
+		t.Fatal(s)
+	}
+}
+
+func TestNamedWindow(t *testing.T) {
+	q, err := ParseQuery("SELECT RANK() OVER w, SUM(amount) OVER (w ROWS 1 PRECEDING) FROM sales WINDOW w AS (PARTITION BY region ORDER BY amount)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, nil, "", "mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT RANK() OVER w, SUM(amount) OVER (w ROWS 1 PRECEDING) FROM sales WINDOW w AS (PARTITION BY region ORDER BY amount)` {
+		t.Fatal(s)
+	}
+}
+
+func TestWindowColumns(t *testing.T) {
+	q, err := ParseQuery(`select end, window, over, rank() over w from sales window w as (order by id)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, nil, "", "sqlite3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT end, window, over, RANK() OVER w FROM sales WINDOW w AS (ORDER BY id)` {
+		t.Fatal(s)
+	}
+}
+
+func TestWindowNamespace(t *testing.T) {
+	q, err := ParseQuery("SELECT u.id, COUNT(*) OVER (PARTITION BY u.account) FROM users u")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWriter(q, nil, "", "mysql")
+	w.Namespace = "ns"
+	w.EscapeIdents = false
+
+	s, _, err := w.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT u.id, COUNT(*) OVER (PARTITION BY u.account) FROM ns_users AS u` {
+		t.Fatal(s)
+	}
+}
+
+func TestSetOperations(t *testing.T) {
+	q, err := ParseQuery("select id from a union all select id from b intersect select id from c except distinct select id from d order by id limit ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, []interface{}{10}, "", "sqlite3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT id FROM a UNION ALL SELECT id FROM b INTERSECT SELECT id FROM c EXCEPT SELECT id FROM d ORDER BY id LIMIT ?` {
+		t.Fatal(s)
+	}
+
+	sel := q.(*SelectQuery)
+	if len(sel.UnionPart) != 3 || !sel.UnionPart[0].All || sel.UnionPart[1].Type != INTERSECT {
+		t.Fatal(sel.UnionPart)
+	}
+
+	if sel.UnionPart[2].Query.OrderByPart != nil || sel.OrderByPart == nil {
+		t.Fatal("The ORDER BY must apply to the compound")
+	}
+}
+
+func TestSetOperationsColumns(t *testing.T) {
+	q, err := ParseQuery("select intersect, except from a except select intersect, except from b except")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, nil, "", "sqlite3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT intersect, except FROM a EXCEPT SELECT intersect, except FROM b AS except` {
+		t.Fatal(s)
+	}
+}
+
+func TestSetOperationsParams(t *testing.T) {
+	q, err := ParseQuery("select id from a where x = ? union select id from b where y = ? limit ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, []interface{}{1, 2, 3}, "", "postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT id FROM a WHERE x = $1 UNION SELECT id FROM b WHERE y = $2 LIMIT $3` {
+		t.Fatal(s)
+	}
+}
+
+func TestEmulateIntersect(t *testing.T) {
+	q, err := ParseQuery("select id, name as n from a where x = ? intersect select id, name from b where y = ? order by id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWriter(q, []interface{}{1, 2}, "", "mysql")
+	w.Dialect = MySQLDialect{Version: "5.7"}
+	w.EscapeIdents = false
+
+	s, _, err := w.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT id, n FROM (SELECT *, 1 AS goql_set FROM (SELECT id, name AS n FROM a WHERE x = ?) AS goql_a `+
+		`UNION ALL SELECT *, 2 FROM (SELECT id, name FROM b WHERE y = ?) AS goql_b) AS goql_t `+
+		`GROUP BY id, n HAVING COUNT(DISTINCT goql_set) = 2 ORDER BY id` {
+		t.Fatal(s)
+	}
+}
+
+func TestEmulateExcept(t *testing.T) {
+	q, err := ParseQuery("select id from a union select id from b except select id from c")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWriter(q, nil, "", "mysql")
+	w.Dialect = MySQLDialect{Version: "8.0.30"}
+	w.EscapeIdents = false
+
+	s, _, err := w.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT id FROM (SELECT *, 1 AS goql_set FROM (SELECT id FROM a UNION SELECT id FROM b) AS goql_a `+
+		`UNION ALL SELECT *, 2 FROM (SELECT id FROM c) AS goql_b) AS goql_t GROUP BY id HAVING MAX(goql_set) = 1` {
+		t.Fatal(s)
+	}
+
+	// the latest version supports it
+	s, _, err = toSQL(false, q, nil, "", "mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT id FROM a UNION SELECT id FROM b EXCEPT SELECT id FROM c` {
+		t.Fatal(s)
+	}
+}
+
+func TestEmulateIntersectErrors(t *testing.T) {
+	for _, code := range []string{
+		"select * from a intersect select * from b",
+		"select id from a intersect all select id from b",
+		"select id from a union select id from b intersect select id from c",
+	} {
+		q, err := ParseQuery(code)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := NewWriter(q, nil, "", "mysql")
+		w.Dialect = MySQLDialect{Version: "5.7"}
+
+		if _, _, err := w.Write(); err == nil {
+			t.Fatal("Expected error: " + code)
+		}
+	}
+}
+
+func TestDerivedTable(t *testing.T) {
+	q, err := ParseQuery("select t.a, x.b from (select a from foo where c = ?) t join (select b, a from bar) as x on x.a = t.a where x.b > ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, []interface{}{1, 2}, "", "postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT t.a, x.b FROM (SELECT a FROM foo WHERE c = $1) AS t JOIN (SELECT b, a FROM bar) AS x ON x.a = t.a WHERE x.b > $2` {
+		t.Fatal(s)
+	}
+
+	sel := q.(*SelectQuery)
+	d, ok := sel.From[0].(*DerivedTable)
+	if !ok || d.Alias != "t" || len(d.Joins) != 1 || d.Joins[0].Subquery == nil {
+		t.Fatal(sel.From)
+	}
+}
+
+func TestDerivedTableUpdate(t *testing.T) {
+	q, err := ParseQuery("update client c join (select idClient, sum(total) as total from sale group by idClient) s on s.idClient = c.id set c.total = s.total")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWriter(q, nil, "", "mysql")
+	w.Namespace = "foo"
+	w.EscapeIdents = false
+
+	s, _, err := w.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `UPDATE foo_client AS c JOIN (SELECT idClient, SUM(total) AS total FROM foo_sale GROUP BY idClient) AS s ON s.idClient = c.id SET c.total = s.total` {
+		t.Fatal(s)
+	}
+}
+
+func TestJoinUsing(t *testing.T) {
+	q, err := ParseQuery("select * from orders o left join customers c using (idCustomer, idShop) natural join shops")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, driver := range []string{"mysql", "sqlite3"} {
+		s, _, err := toSQL(false, q, nil, "", driver)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s != `SELECT * FROM orders AS o LEFT JOIN customers AS c USING (idCustomer, idShop) NATURAL JOIN shops` {
+			t.Fatal(s)
+		}
+	}
+
+	j := q.(*SelectQuery).From[0].(*Table).Joins
+	if len(j) != 2 || len(j[0].Using) != 2 || !j[1].Natural {
+		t.Fatal(j)
+	}
+}
+
+func TestNaturalLeftJoin(t *testing.T) {
+	q, err := ParseQuery("select * from orders natural left join (select idCustomer, name from customers) c")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, nil, "", "sqlite3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT * FROM orders NATURAL LEFT JOIN (SELECT idCustomer, name FROM customers) AS c` {
+		t.Fatal(s)
+	}
+
+	if _, err := ParseQuery("select * from orders natural cross join customers"); err == nil {
+		t.Fatal("Expected error")
+	}
+}
+
+func TestNaturalUsingAlias(t *testing.T) {
+	q, err := ParseQuery("select natural.id, using.name from orders natural, customers using where natural.id = using.id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, nil, "", "sqlite3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT natural.id, using.name FROM orders AS natural, customers AS using WHERE natural.id = using.id` {
+		t.Fatal(s)
+	}
+}
+
+func TestFullJoin(t *testing.T) {
+	q, err := ParseQuery("select * from a full outer join b on a.id = b.id left outer join c on c.id = a.id right join d on d.id = c.id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, nil, "", "sqlite3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT * FROM a FULL JOIN b ON a.id = b.id LEFT JOIN c ON c.id = a.id RIGHT JOIN d ON d.id = c.id` {
+		t.Fatal(s)
+	}
+
+	// full is not reserved
+	q, err = ParseQuery("select full.id from a full")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err = toSQL(false, q, nil, "", "sqlite3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT full.id FROM a AS full` {
+		t.Fatal(s)
+	}
+}
+
+func TestEmulateRightJoin(t *testing.T) {
+	q, err := ParseQuery("select * from (select id from a where x = ?) a right join (select id from b where y = ?) b on a.id = b.id join c on c.id = b.id where c.z = ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWriter(q, []interface{}{1, 2, 3}, "", "sqlite3")
+	w.Dialect = SQLiteDialect{Version: "3.31"}
+	w.EscapeIdents = false
+
+	s, params, err := w.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// * is expanded so the columns are in the same order as in a RIGHT JOIN.
+	if s != `SELECT a.*, b.*, c.* FROM (SELECT id FROM b WHERE y = ?) AS b LEFT JOIN (SELECT id FROM a WHERE x = ?) AS a ON a.id = b.id JOIN c ON c.id = b.id WHERE c.z = ?` {
+		t.Fatal(s)
+	}
+
+	if len(params) != 3 || params[0] != 2 || params[1] != 1 || params[2] != 3 {
+		t.Fatal(params)
+	}
+
+	// only the first join can be emulated
+	q, err = ParseQuery("select * from a join b on a.id = b.id right join c on c.id = b.id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w = NewWriter(q, nil, "", "sqlite3")
+	w.Dialect = SQLiteDialect{Version: "3.31"}
+
+	if _, _, err := w.Write(); err == nil {
+		t.Fatal("Expected error")
+	}
+}
+
+func TestEmulateFullJoin(t *testing.T) {
+	q, err := ParseQuery("select a.id, b.name from a full join b on a.id = b.id and b.x = ? where a.y = ? or b.y = ? order by a.id limit ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWriter(q, []interface{}{1, 2, 3, 4}, "", "sqlite3")
+	w.Dialect = SQLiteDialect{Version: "3.38.5"}
+	w.EscapeIdents = false
+
+	s, params, err := w.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT a.id, b.name FROM a LEFT JOIN b ON a.id = b.id AND b.x = ? WHERE a.y = ? OR b.y = ? `+
+		`UNION ALL SELECT a.id, b.name FROM b LEFT JOIN a ON a.id = b.id AND b.x = ? `+
+		`WHERE NOT EXISTS (SELECT 1 FROM a WHERE a.id = b.id AND b.x = ?) AND (a.y = ? OR b.y = ?) `+
+		`ORDER BY a.id LIMIT ?` {
+		t.Fatal(s)
+	}
+
+	expected := []interface{}{1, 2, 3, 1, 1, 2, 3, 4}
+	if len(params) != len(expected) {
+		t.Fatal(params)
+	}
+	for i, v := range expected {
+		if params[i] != v {
+			t.Fatal(params)
+		}
+	}
+
+	// mysql doesn't support FULL JOIN
+	s, _, err = toSQL(false, q, []interface{}{1, 2, 3, 4}, "", "mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(s, " UNION ALL ") {
+		t.Fatal(s)
+	}
+
+	// both selects return the columns of * in the same order.
+	q, err = ParseQuery("select * from clients c full join orders on orders.idClient = c.id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err = toSQL(false, q, nil, "db", "mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != `SELECT c.*, db.orders.* FROM db.clients AS c LEFT JOIN db.orders ON orders.idClient = c.id `+
+		`UNION ALL SELECT c.*, db.orders.* FROM db.orders LEFT JOIN db.clients AS c ON orders.idClient = c.id `+
+		`WHERE NOT EXISTS (SELECT 1 FROM db.clients AS c WHERE orders.idClient = c.id)` {
+		t.Fatal(s)
+	}
+
+	// USING returns its columns only once.
+	q, err = ParseQuery("select * from a right join b using (id)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w = NewWriter(q, nil, "", "sqlite3")
+	w.Dialect = SQLiteDialect{Version: "3.31"}
+
+	if _, _, err := w.Write(); err == nil {
+		t.Fatal("Expected error")
+	}
+}
+
+func TestCastExpr(t *testing.T) {
+	q, err := ParseQuery("select cast(total as decimal(10,2)), convert(id, char), cast(? as signed integer), cast(n as unsigned) from foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"mysql":    "SELECT CAST(total AS DECIMAL(10,2)), CAST(id AS CHAR), CAST(? AS SIGNED), CAST(n AS UNSIGNED) FROM foo",
+		"sqlite3":  "SELECT CAST(total AS REAL), CAST(id AS TEXT), CAST(? AS INTEGER), CAST(n AS INTEGER) FROM foo",
+		"postgres": "SELECT CAST(total AS NUMERIC(10,2)), CAST(id AS VARCHAR), CAST($1 AS INTEGER), CAST(n AS BIGINT) FROM foo",
+	}
+
+	for driver, expected := range tests {
+		s, _, err := toSQL(false, q, []interface{}{1}, "", driver)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s != expected {
+			t.Fatal(s)
+		}
+	}
+}
+
+func TestCastExprInvalid(t *testing.T) {
+	for _, code := range []string{
+		"select cast(a) from foo",
+		"select cast(a as foo) from foo",
+		"select convert(a using utf8) from foo",
+	} {
+		if _, err := ParseQuery(code); err == nil {
+			t.Fatal("Expected error: " + code)
+		}
+	}
+}
+
+func TestStringEscape(t *testing.T) {
+	data := []struct {
+		code   string
+		value  string
+		mysql  string
+		sqlite string
+	}{
+		{`'it''s'`, "it's", `'it''s'`, `'it''s'`},
+		{`'it\'s'`, "it's", `'it''s'`, `'it''s'`},
+		{`"say ""hi"""`, `say "hi"`, `'say "hi"'`, `'say "hi"'`},
+		{`'a\\b\nc'`, "a\\b\nc", "'a\\\\b\nc'", "'a\\b\nc'"},
+		{`'\\'' OR 1=1 -- '`, `\' OR 1=1 -- `, `'\\'' OR 1=1 -- '`, `'\'' OR 1=1 -- '`},
+		{`'a\0b'`, "a\x00b", `'a\0b'`, "'a\x00b'"},
+		{`'50\%'`, `50\%`, `'50\\%'`, `'50\%'`},
+	}
+
+	for _, d := range data {
+		q, err := ParseQuery("SELECT " + d.code)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c := q.(*SelectQuery).Columns[0].(*ConstantExpr)
+		if c.Value != d.value {
+			t.Fatal(c.Value)
+		}
+
+		for driver, expected := range map[string]string{"mysql": d.mysql, "sqlite3": d.sqlite, "postgres": d.sqlite} {
+			s, _, err := toSQL(false, q, nil, "", driver)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if s != "SELECT "+expected {
+				t.Fatal(s)
+			}
+
+			if driver == "mysql" {
+				// the value is the same parsing it again
+				q, err := ParseQuery(s)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if v := q.(*SelectQuery).Columns[0].(*ConstantExpr).Value; v != d.value {
+					t.Fatal(v)
+				}
+			}
+		}
+	}
+}
+
+func TestNumericLiterals(t *testing.T) {
+	q, err := ParseQuery("SELECT 0x1F, b'101', X'4142', 1.5e-3, .5, -2, 3 - -1, b'', 0xFFFFFFFFFFFFFFFF FROM foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"mysql":    "SELECT 0x1F, b'101', X'4142', 1.5e-3, .5, -2, 3 - -1, b'', 0xFFFFFFFFFFFFFFFF FROM foo",
+		"sqlite3":  "SELECT 31, 5, X'4142', 1.5e-3, .5, -2, 3 - -1, X'', 18446744073709551615 FROM foo",
+		"postgres": "SELECT 31, 5, DECODE('4142', 'hex'), 1.5e-3, .5, -2, 3 - -1, DECODE('', 'hex'), 18446744073709551615 FROM foo",
+	}
+
+	for driver, expected := range tests {
+		s, _, err := toSQL(false, q, nil, "", driver)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s != expected {
+			t.Fatal(s)
+		}
+	}
+}
+
+func TestNumericLiteralsInvalid(t *testing.T) {
+	for _, c := range []*ConstantExpr{
+		{Kind: INT, Value: "1 OR 1=1"},
+		{Kind: FLOAT, Value: "."},
+		{Kind: FLOAT, Value: "1e--1"},
+		{Kind: HEX, Value: ""},
+		{Kind: HEXSTRING, Value: "ABC"},
+		{Kind: BIT, Value: "12"},
+		{Kind: HEX, Value: "1FFFFFFFFFFFFFFFF"},
+		{Kind: BIT, Value: "1" + strings.Repeat("0", 64)},
+	} {
+		q := &SelectQuery{Columns: []Expr{c}}
+		for _, driver := range []string{"mysql", "sqlite3", "postgres"} {
+			if _, _, err := toSQL(false, q, nil, "", driver); err == nil {
+				t.Fatalf("Expected error in %s: %s", driver, c.Value)
+			}
+		}
+	}
+
+	// a unary minus never writes a comment
+	q := &SelectQuery{Columns: []Expr{&UnaryExpr{Operator: SUB, Operand: &ConstantExpr{Kind: INT, Value: "-1"}}}}
+	s, _, err := toSQL(false, q, nil, "", "mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "SELECT - -1" {
+		t.Fatal(s)
+	}
+}
+
+func TestBitwiseOperators(t *testing.T) {
+	q, err := ParseQuery("SELECT a << 2, b >> 1, a | b & c, a + b << 2, ~a, a ^ b FROM foo WHERE a ^ ? = ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"mysql":    "SELECT a << 2, b >> 1, a | (b & c), (a + b) << 2, ~a, a ^ b FROM foo WHERE (a ^ ?) = ?",
+		"sqlite3":  "SELECT a << 2, b >> 1, a | (b & c), (a + b) << 2, ~a, (a | b) - (a & b) FROM foo WHERE ((a | ?) - (a & ?)) = ?",
+		"postgres": "SELECT a << 2, b >> 1, a | (b & c), (a + b) << 2, ~a, a # b FROM foo WHERE (a # $1) = $2",
+	}
+
+	for driver, expected := range tests {
+		s, params, err := toSQL(false, q, []interface{}{1, 2}, "", driver)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s != expected {
+			t.Fatal(s)
+		}
+
+		if driver == "sqlite3" && (len(params) != 3 || params[0] != 1 || params[1] != 1 || params[2] != 2) {
+			t.Fatal(params)
+		}
+	}
+}
+
+func TestNestedUnaryOperators(t *testing.T) {
+	q, err := ParseQuery("SELECT ~-1, -~a, - -3, +-a, ~~a FROM foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, nil, "", "mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "SELECT ~ -1, - ~a, - -3, + -a, ~ ~a FROM foo" {
+		t.Fatal(s)
+	}
+
+	sel := q.(*SelectQuery)
+	u, ok := sel.Columns[2].(*UnaryExpr)
+	if !ok || u.Operator != SUB {
+		t.Fatal(sel.Columns[2])
+	}
+
+	// only the direct literal is folded into a negative constant
+	if c, ok := u.Operand.(*ConstantExpr); !ok || c.Value != "-3" {
+		t.Fatal(u.Operand)
+	}
+}
+
+func TestConcat(t *testing.T) {
+	q, err := ParseQuery("SELECT a || b || 'x', a || b * 2 FROM foo WHERE a || ? = 'ab'")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"mysql":    "SELECT CONCAT(a, b, 'x'), CONCAT(a, b) * 2 FROM foo WHERE CONCAT(a, ?) = 'ab'",
+		"sqlite3":  "SELECT a || b || 'x', (a || b) * 2 FROM foo WHERE (a || ?) = 'ab'",
+		"postgres": "SELECT a || b || 'x', (a || b) * 2 FROM foo WHERE (a || $1) = 'ab'",
+	}
+
+	for driver, expected := range tests {
+		s, _, err := toSQL(false, q, []interface{}{1}, "", driver)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s != expected {
+			t.Fatal(s)
+		}
+	}
+}
+
+func TestMatchOperators(t *testing.T) {
+	q, err := ParseQuery(`SELECT id FROM foo WHERE a LIKE '10!%' ESCAPE '!' AND b NOT ILIKE ? AND c RLIKE '^x' AND d NOT REGEXP 'y$'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"mysql":    `SELECT id FROM foo WHERE a LIKE '10!%' ESCAPE '!' AND LOWER(b) NOT LIKE LOWER(?) AND c REGEXP '^x' AND d NOT REGEXP 'y$'`,
+		"sqlite3":  `SELECT id FROM foo WHERE a LIKE '10!%' ESCAPE '!' AND LOWER(b) NOT LIKE LOWER(?) AND c REGEXP '^x' AND d NOT REGEXP 'y$'`,
+		"postgres": `SELECT id FROM foo WHERE a LIKE '10!%' ESCAPE '!' AND b NOT ILIKE $1 AND c ~ '^x' AND d !~ 'y$'`,
+	}
+
+	for driver, expected := range tests {
+		s, _, err := toSQL(false, q, []interface{}{"x"}, "", driver)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s != expected {
+			t.Fatal(s)
+		}
+	}
+}
+
+func TestGlob(t *testing.T) {
+	q, err := ParseQuery("SELECT id FROM foo WHERE name GLOB 'a*' AND name NOT GLOB '*b'")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := toSQL(false, q, nil, "", "sqlite3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "SELECT id FROM foo WHERE name GLOB 'a*' AND name NOT GLOB '*b'" {
+		t.Fatal(s)
+	}
+
+	for _, driver := range []string{"mysql", "postgres"} {
+		if _, _, err := toSQL(false, q, nil, "", driver); err == nil {
+			t.Fatal("Expected error: " + driver)
+		}
+	}
+
+	// ESCAPE is only for LIKE
+	if _, err := ParseQuery("SELECT id FROM foo WHERE name GLOB 'a*' ESCAPE '!'"); err == nil {
+		t.Fatal("Expected error")
+	}
+}
+
+func TestIsDistinct(t *testing.T) {
+	q, err := ParseQuery("SELECT id FROM foo WHERE a IS DISTINCT FROM b OR a IS NOT DISTINCT FROM ? OR c <=> d")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"mysql":    "SELECT id FROM foo WHERE NOT (a <=> b) OR a <=> ? OR c <=> d",
+		"sqlite3":  "SELECT id FROM foo WHERE a IS NOT b OR a IS ? OR c IS d",
+		"postgres": "SELECT id FROM foo WHERE a IS DISTINCT FROM b OR a IS NOT DISTINCT FROM $1 OR c IS NOT DISTINCT FROM d",
+	}
+
+	for driver, expected := range tests {
+		s, params, err := toSQL(false, q, []interface{}{nil}, "", driver)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s != expected {
+			t.Fatal(s)
+		}
+
+		// a nil parameter is compared as a value
+		if len(params) != 1 || params[0] != nil {
+			t.Fatal(params)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	p := NewStrParser(`
+		/* the header */
+		# the users
+		SELECT id -- the key
+		FROM /* inline */ users; -- trailing
+		-- the products
+		DELETE FROM products; /* end */
+		-- last
+	`)
+
+	queries, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(queries) != 2 {
+		t.Fatal(len(queries))
+	}
+
+	c := queries[0].QueryComments()
+	if len(c.Leading) != 2 || c.Leading[0].Text != "/* the header */" || c.Leading[1].Text != "# the users" {
+		t.Fatal(c.Leading)
+	}
+
+	if len(c.Trailing) != 1 || c.Trailing[0].Text != "-- trailing" {
+		t.Fatal(c.Trailing)
+	}
+
+	w := NewWriter(queries[0], nil, "", "mysql")
+	w.EscapeIdents = false
+	w.Comments = true
+	s, _, err := w.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "/* the header */\n-- the users\nSELECT id FROM users -- trailing\n" {
+		t.Fatal(s)
+	}
+
+	w = NewWriter(queries[1], nil, "", "mysql")
+	w.EscapeIdents = false
+	w.Comments = true
+	s, _, err = w.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "-- the products\nDELETE FROM products /* end */ -- last\n" {
+		t.Fatal(s)
+	}
+
+	// by default comments are not written
+	s, _, err = toSQL(false, queries[1], nil, "", "mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "DELETE FROM products" {
+		t.Fatal(s)
+	}
+}
+
+func TestCommentsInvalid(t *testing.T) {
+	for _, text := range []string{
+		"/* foo */ DROP TABLE users /* bar */",
+		"-- foo\nDROP TABLE users",
+		"/*/",
+		"foo",
+		"/*!40101 SET NAMES utf8 */",
+		"/*+ NO_INDEX(users) */",
+	} {
+		q, err := ParseQuery("SELECT 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		q.QueryComments().Leading = []*Comment{{Text: text}}
+
+		w := NewWriter(q, nil, "", "mysql")
+		w.Comments = true
+		if _, _, err := w.Write(); err == nil {
+			t.Fatal("Expected error: " + text)
+		}
+	}
+}
+
 func toSQL(format bool, q Query, params []interface{}, database, driver string) (string, []interface{}, error) {
 	w := NewWriter(q, params, database, driver)
 	w.EscapeIdents = false